@@ -1,23 +1,48 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"movierental/config"
 	"movierental/pkg/database"
+	"movierental/pkg/logging"
+	"movierental/pkg/metrics"
 	"movierental/pkg/routes"
+	"movierental/pkg/server"
+	"movierental/pkg/tracing"
+	"movierental/pkg/utils"
+	"movierental/pkg/worker"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/errgroup"
 )
 
-func init() {
+// setup loads the configuration and applies the process-wide settings that
+// depend on it.
+func setup() error {
+	configPath := flag.String("config", "", "path to the config file (JSON, YAML or TOML); defaults to $"+config.PathEnv+" or config/config.json")
+	flag.Parse()
 
-	err := config.LoadConfig()
-	if err != nil {
-		panic(err)
+	if err := config.LoadConfig(*configPath); err != nil {
+		return err
 	}
 
-	fmt.Println(config.AppConfig)
-	database.ConnectToDb()
+	logging.Setup(config.AppConfig.Logging)
+	slog.Info("Configuration loaded", "environment", config.AppConfig.Environment, "port", config.AppConfig.Port)
+	gin.SetMode(config.AppConfig.GinMode)
+	if err := utils.ConfigurePasswordHashing(config.AppConfig.Security.PasswordHashing); err != nil {
+		return fmt.Errorf("invalid password hashing settings: %w", err)
+	}
+	utils.ConfigurePasswordPolicy(config.AppConfig.Security.PasswordPolicy)
+	if err := utils.ConfigureSigningKey(config.AppConfig.Security.JWTSecret); err != nil {
+		return fmt.Errorf("invalid security.jwt_secret: %w", err)
+	}
+	return nil
 }
 
 // Swagger API Documentation Route
@@ -38,10 +63,92 @@ func init() {
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
 func main() {
-	router := gin.Default()
+	if err := setup(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := run(); err != nil {
+		slog.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run serves requests until the process receives SIGINT or SIGTERM, then
+// shuts down in order: the HTTP server, background jobs, the database pool
+// and finally the trace exporter.
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := tracing.Setup(ctx, config.AppConfig.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), server.ShutdownTimeout(config.AppConfig.Server))
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("Failed to flush traces", "error", err)
+		}
+	}()
 
-	routes.SetupRoutes(router)
+	db, err := database.Connect(ctx, config.AppConfig.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			if err := sqlDB.Close(); err != nil {
+				slog.Error("Failed to close database connections", "error", err)
+			}
+		}
+	}()
+	if err := database.CheckMigrations(ctx, db); err != nil {
+		return fmt.Errorf("refusing to start: %w; run `go run ./migrate up` first", err)
+	}
+	if err := metrics.InstrumentDB(db); err != nil {
+		return err
+	}
+	if err := tracing.InstrumentDB(db); err != nil {
+		return err
+	}
 
-	router.Run(":" + config.AppConfig.Port)
+	router, err := server.NewRouter(config.AppConfig.Server)
+	if err != nil {
+		return err
+	}
+	workers := worker.NewGroup()
+	defer workers.Stop()
+
+	routes.SetupRoutes(router, db, workers)
+
+	// Both servers stop together: on a signal, or when either one fails.
+	servers, serversCtx := errgroup.WithContext(ctx)
+	shutdownTimeout := server.ShutdownTimeout(config.AppConfig.Server)
+	servers.Go(func() error {
+		srv := server.New(":"+config.AppConfig.Port, config.AppConfig.Server, router)
+		if err := server.Run(serversCtx, srv, shutdownTimeout); err != nil {
+			return fmt.Errorf("HTTP server stopped: %w", err)
+		}
+		return nil
+	})
+	if address := config.AppConfig.Metrics.Address; address != "" {
+		servers.Go(func() error {
+			srv := server.New(address, config.AppConfig.Server, metrics.NewServeMux(config.AppConfig.Metrics.BearerToken))
+			slog.Info("Serving metrics", "address", address)
+			if err := server.Run(serversCtx, srv, shutdownTimeout); err != nil {
+				return fmt.Errorf("metrics server stopped: %w", err)
+			}
+			return nil
+		})
+	}
+	if err := servers.Wait(); err != nil {
+		return err
+	}
+	slog.Info("Server stopped")
+	return nil
 }
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"movierental/config"
+	"movierental/pkg/fixtures"
+	"movierental/pkg/logging"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/services"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ctl runs commands against the services of one database.
+type ctl struct {
+	db       *gorm.DB
+	users    *services.UserService
+	carts    *services.CartService
+	sessions *services.SessionService
+	apiKeys  *services.APIKeyService
+	in       *bufio.Reader
+	out      io.Writer
+	// operator is recorded in the audit log as the actor.
+	operator string
+}
+
+func newCtl(db *gorm.DB, in *bufio.Reader, out io.Writer, operator string) *ctl {
+	store := repository.NewStore(db)
+	sessions := services.NewSessionService(store, config.AppConfig.Security.Sessions, config.AppConfig.Security.MFA)
+	return &ctl{
+		db: db,
+		users: &services.UserService{
+			Store:            store,
+			MFAPolicy:        config.AppConfig.Security.MFA,
+			PasswordResetTTL: time.Duration(config.AppConfig.Security.PasswordPolicy.ResetTokenTTLMinutes) * time.Minute,
+			Sessions:         sessions,
+		},
+		carts:    services.NewCartService(store.Carts),
+		sessions: sessions,
+		apiKeys:  services.NewAPIKeyService(store),
+		in:       in,
+		out:      out,
+		operator: operator,
+	}
+}
+
+// run dispatches args, e.g. ["user", "show", "alice"], to its command.
+func (c *ctl) run(ctx context.Context, args []string) error {
+	if len(args) > 0 && args[0] == "seed" {
+		return c.seed(ctx, args[1:])
+	}
+	if len(args) < 2 {
+		return errUsage
+	}
+	commands := map[string]func(context.Context, []string) error{
+		"user create":         c.createUser,
+		"user show":           c.showUser,
+		"user reset-password": c.resetPassword,
+		"cart list":           c.listCarts,
+		"cart show":           c.showCart,
+		"sessions list":       c.listSessions,
+		"sessions revoke":     c.revokeSessions,
+		"apikeys list":        c.listAPIKeys,
+		"apikeys revoke":      c.revokeAPIKey,
+	}
+	command, ok := commands[args[0]+" "+args[1]]
+	if !ok {
+		return errUsage
+	}
+	return command(ctx, args[2:])
+}
+
+// parseArgs parses the flags of a command and checks it got between min and
+// max positional arguments.
+func parseArgs(flags *flag.FlagSet, args []string, min int, max int) ([]string, error) {
+	flags.SetOutput(io.Discard)
+	if err := flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if flags.NArg() < min || flags.NArg() > max {
+		return nil, errUsage
+	}
+	return flags.Args(), nil
+}
+
+func (c *ctl) createUser(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("user create", flag.ContinueOnError)
+	role := flags.String("role", models.RoleUser, "role of the new account")
+	args, err := parseArgs(flags, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	password, err := readSecret(c.in, "Password")
+	if err != nil {
+		return err
+	}
+
+	response, err := c.users.CreateUserWithRole(ctx, requests.CreateUser{Username: args[0], Email: args[1], Password: password}, *role)
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) showUser(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("user show", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, gin.H{
+		"id":           user.ID,
+		"username":     user.Username,
+		"email":        user.Email,
+		"role":         user.Role,
+		"totp_enabled": user.TOTPEnabled,
+	})
+}
+
+// resetPassword issues a reset token to hand to the user or, with --set,
+// redeems it right away with a password read from stdin. Either way the
+// password policy applies and, once changed, the user's sessions end.
+func (c *ctl) resetPassword(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	set := flags.Bool("set", false, "read the new password from stdin and apply it")
+	args, err := parseArgs(flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	var password string
+	if *set {
+		if password, err = readSecret(c.in, "New password"); err != nil {
+			return err
+		}
+	}
+	issued, err := c.users.CreatePasswordReset(ctx, c.operator, user.ID)
+	if err != nil {
+		return err
+	}
+	if !*set {
+		return printJSON(c.out, issued)
+	}
+
+	response, err := c.users.ResetPassword(ctx, requests.ResetPassword{Token: issued["reset_token"].(string), NewPassword: password})
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) listCarts(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("cart list", flag.ContinueOnError)
+	limit := flags.Int("limit", 50, "maximum number of carts to list")
+	offset := flags.Int("offset", 0, "number of carts to skip")
+	if _, err := parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	if *limit < 1 || *offset < 0 {
+		return fmt.Errorf("%w: --limit must be positive and --offset not negative", errUsage)
+	}
+
+	response, err := c.carts.ListCarts(ctx, *limit, *offset)
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) showCart(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("cart show", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	cart, err := c.carts.RetrieveCart(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, gin.H{
+		"cart_id": cart.Id,
+		"user_id": cart.UserId,
+		"movies":  []requests.CartMovieItem(cart.Movies),
+	})
+}
+
+func (c *ctl) listSessions(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("sessions list", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	response, err := c.sessions.ListSessions(ctx, user.ID, "")
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) revokeSessions(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("sessions revoke", flag.ContinueOnError), args, 1, 2)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	var response map[string]interface{}
+	if len(args) == 2 {
+		response, err = c.sessions.RevokeSession(ctx, user.ID, args[1])
+	} else {
+		response, err = c.sessions.RevokeAllSessions(ctx, user.ID, c.operator)
+	}
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) listAPIKeys(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("apikeys list", flag.ContinueOnError), args, 1, 1)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	response, err := c.apiKeys.ListAPIKeys(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+func (c *ctl) revokeAPIKey(ctx context.Context, args []string) error {
+	args, err := parseArgs(flag.NewFlagSet("apikeys revoke", flag.ContinueOnError), args, 2, 2)
+	if err != nil {
+		return err
+	}
+	user, err := c.users.FindUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	response, err := c.apiKeys.RevokeAPIKey(ctx, user.ID, args[1])
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, response)
+}
+
+// seed loads fixture files, see package fixtures.
+func (c *ctl) seed(ctx context.Context, args []string) error {
+	paths, err := parseArgs(flag.NewFlagSet("seed", flag.ContinueOnError), args, 1, math.MaxInt)
+	if err != nil {
+		return err
+	}
+	result, err := fixtures.LoadFiles(ctx, c.db, paths...)
+	if err != nil {
+		return err
+	}
+	return printJSON(c.out, result)
+}
+
+func printJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// redactedConfig returns cfg as a generic JSON document with the values of
+// secret fields, such as passwords, client secrets and API keys, replaced.
+// Empty values are kept so an unset secret still shows as unset.
+func redactedConfig(cfg config.Config) (interface{}, error) {
+	if cfg.Database.URL != "" {
+		if parsed, err := url.Parse(cfg.Database.URL); err == nil && parsed.User != nil {
+			cfg.Database.URL = parsed.Redacted()
+		} else if err != nil || parsed.Scheme == "" {
+			// A key=value DSN may carry a password anywhere.
+			cfg.Database.URL = "[REDACTED]"
+		}
+	}
+
+	encoded, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return nil, fmt.Errorf("failed to encode configuration: %w", err)
+	}
+	return redactValues("", document), nil
+}
+
+func redactValues(key string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for k, v := range value {
+			value[k] = redactValues(k, v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = redactValues(key, v)
+		}
+	case string:
+		if value != "" && logging.IsSensitiveKey(key) {
+			return "[REDACTED]"
+		}
+	}
+	return value
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/models"
+	"movierental/pkg/services"
+	"movierental/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestCtl(t *testing.T) {
+	config.AppConfig = config.Defaults()
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	run := func(t *testing.T, stdin string, args ...string) (map[string]interface{}, error) {
+		t.Helper()
+		var out bytes.Buffer
+		err := newCtl(testDB, bufio.NewReader(strings.NewReader(stdin)), &out, "movierentalctl:test").run(context.Background(), args)
+		if err != nil {
+			return nil, err
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(out.Bytes(), &response); err != nil {
+			t.Fatalf("Expected JSON output, got %q: %v", out.String(), err)
+		}
+		return response, nil
+	}
+
+	t.Run("Create an admin", func(t *testing.T) {
+		if _, err := run(t, "Str0ng-Passw0rd!\n", "user", "create", "--role", "admin", "Root", "root@example.com"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		response, err := run(t, "", "user", "show", "root")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["role"] != models.RoleAdmin || response["email"] != "root@example.com" {
+			t.Errorf("Expected the admin account, got %v", response)
+		}
+		if _, ok := response["password"]; ok {
+			t.Error("Expected the password hash not to be shown")
+		}
+	})
+
+	t.Run("The password policy applies", func(t *testing.T) {
+		if _, err := run(t, "short\n", "user", "create", "weak", "weak@example.com"); err == nil {
+			t.Error("Expected a weak password to be rejected")
+		}
+	})
+
+	t.Run("Signup rules for usernames and emails apply", func(t *testing.T) {
+		if _, err := run(t, "Str0ng-Passw0rd!\n", "user", "create", "ops@example.com", "ops@example.com"); !errors.Is(err, services.ErrInvalidUsername) {
+			t.Errorf("Expected ErrInvalidUsername, got: %v", err)
+		}
+		if _, err := run(t, "Str0ng-Passw0rd!\n", "user", "create", "ops", "not-an-email"); !errors.Is(err, services.ErrInvalidEmail) {
+			t.Errorf("Expected ErrInvalidEmail, got: %v", err)
+		}
+	})
+
+	t.Run("Set a password", func(t *testing.T) {
+		if _, err := run(t, "An0ther-Passw0rd!\n", "user", "reset-password", "--set", "root@example.com"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var user models.User
+		testDB.Where("username = ?", "root").First(&user)
+		if !utils.CheckPasswordHash("An0ther-Passw0rd!", user.Password) {
+			t.Error("Expected the new password to be stored")
+		}
+	})
+
+	t.Run("Carts", func(t *testing.T) {
+		response, err := run(t, "", "cart", "list")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if carts := response["carts"].([]interface{}); len(carts) != 1 {
+			t.Errorf("Expected one cart, got %v", carts)
+		}
+		if _, err := run(t, "", "cart", "show", "root"); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("Revoke all sessions", func(t *testing.T) {
+		if _, err := run(t, "", "sessions", "revoke", "root"); err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("Seed", func(t *testing.T) {
+		response, err := run(t, "", "seed", "../../fixtures/demo.yaml")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["created"] != float64(4) {
+			t.Errorf("Expected the demo users to be created, got %v", response)
+		}
+		if _, err := run(t, "", "seed"); !errors.Is(err, errUsage) {
+			t.Errorf("Expected errUsage without a file, got: %v", err)
+		}
+	})
+
+	t.Run("Unknown users and commands", func(t *testing.T) {
+		if _, err := run(t, "", "user", "show", "nobody"); !errors.Is(err, services.ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got: %v", err)
+		}
+		if _, err := run(t, "", "user", "delete", "root"); !errors.Is(err, errUsage) {
+			t.Errorf("Expected errUsage, got: %v", err)
+		}
+		if _, err := run(t, "", "user", "show"); !errors.Is(err, errUsage) {
+			t.Errorf("Expected errUsage for a missing argument, got: %v", err)
+		}
+	})
+}
+
+func TestRedactedConfig(t *testing.T) {
+	cfg := *config.Defaults()
+	cfg.Database.Password = "db-password"
+	cfg.Database.URL = "postgres://app:db-password@db:5432/movies"
+	cfg.MovieAPI.Headers.RapidAPIKey = "rapid-key"
+	cfg.Security.OIDCProviders = map[string]config.OIDCProviderConfig{"google": {ClientID: "client", ClientSecret: "oidc-secret"}}
+
+	document, err := redactedConfig(cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	encoded, _ := json.Marshal(document)
+	for _, secret := range []string{"db-password", "rapid-key", "oidc-secret"} {
+		if strings.Contains(string(encoded), secret) {
+			t.Errorf("Expected %q to be redacted from %s", secret, encoded)
+		}
+	}
+	if !strings.Contains(string(encoded), `"client_id":"client"`) {
+		t.Errorf("Expected other settings to be shown, got %s", encoded)
+	}
+}
@@ -0,0 +1,136 @@
+// Command movierentalctl is the operator's tool for the movie rental
+// service. It talks to the database directly but goes through the same
+// services as the API, so passwords, roles and sessions follow the same
+// rules whichever way they are changed.
+//
+// Run it without arguments for the list of commands.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"movierental/config"
+	"movierental/pkg/database"
+	"movierental/pkg/logging"
+	"movierental/pkg/utils"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+const usage = `usage: movierentalctl [--config path] <command> [arguments]
+
+commands:
+  user create [--role user|staff|admin] <username> <email>
+                                  create an account; the password is read from stdin
+  user show <user>                show an account
+  user reset-password [--set] <user>
+                                  issue a password reset token, or with --set read
+                                  a new password from stdin and apply it
+  cart list [--limit n] [--offset n]
+                                  list carts and how many movies they hold
+  cart show <user>                show the movies in a user's cart
+  sessions list <user>            list a user's active sessions
+  sessions revoke <user> [session-id]
+                                  revoke one session, or every session of the user
+  apikeys list <user>             list a user's API keys
+  apikeys revoke <user> <key-id>  revoke an API key
+  config show                     print the effective configuration, secrets redacted
+  seed <file>...                  load users and carts from YAML or JSON fixture files;
+                                  loading the same files again only applies changes
+
+<user> is a user ID, email or username.
+
+flags:
+`
+
+var errUsage = errors.New("missing or unknown command")
+
+func main() {
+	flags := flag.NewFlagSet("movierentalctl", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the config file (JSON, YAML or TOML); defaults to $"+config.PathEnv+" or config/config.json")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, flags.Args(), *configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, configPath string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+	if err := config.LoadConfig(configPath); err != nil {
+		return err
+	}
+	// Output on stdout is for the operator; only problems are logged.
+	slog.SetDefault(logging.New(os.Stderr, slog.LevelWarn))
+	if err := utils.ConfigurePasswordHashing(config.AppConfig.Security.PasswordHashing); err != nil {
+		return fmt.Errorf("invalid password hashing settings: %w", err)
+	}
+	utils.ConfigurePasswordPolicy(config.AppConfig.Security.PasswordPolicy)
+
+	if args[0] == "config" {
+		if args[1] != "show" {
+			return errUsage
+		}
+		document, err := redactedConfig(*config.AppConfig)
+		if err != nil {
+			return err
+		}
+		return printJSON(os.Stdout, document)
+	}
+
+	db, err := database.Connect(ctx, config.AppConfig.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+	if err := database.CheckMigrations(ctx, db); err != nil {
+		return fmt.Errorf("%w; run `go run ./migrate up` first", err)
+	}
+
+	return newCtl(db, bufio.NewReader(os.Stdin), os.Stdout, operatorName()).run(ctx, args)
+}
+
+// operatorName identifies the person running the tool in audit logs.
+func operatorName() string {
+	if name := os.Getenv("USER"); name != "" {
+		return "movierentalctl:" + name
+	}
+	return "movierentalctl"
+}
+
+// readSecret reads one line from in, prompting for what on stderr. Secrets
+// come from stdin rather than arguments so they stay out of shell history and
+// process listings, and can be piped in by scripts.
+func readSecret(in *bufio.Reader, what string) (string, error) {
+	fmt.Fprintf(os.Stderr, "%s: ", what)
+	line, err := in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("failed to read %s from stdin: %w", strings.ToLower(what), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
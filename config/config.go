@@ -1,18 +1,36 @@
 package config
 
 import (
-	"encoding/json"
-	"fmt"
 	"os"
 )
 
+// DatabaseConfig describes the database connection. Driver is "postgres" or
+// "sqlite"; SQLite stores everything in the file at Path and needs no server.
+// For PostgreSQL, URL, a connection URL or key=value DSN, replaces Host,
+// Port, User, Password, DBName and SSLMode when set. The pool settings map
+// onto database/sql; zero keeps its default.
+// Startup makes ConnectRetries further attempts when the database is not
+// reachable, waiting ConnectRetryBackoffSeconds before the first and twice as
+// long before each next one.
 type DatabaseConfig struct {
+	Driver   string `json:"driver"`
+	Path     string `json:"path"`
+	URL      string `json:"url"`
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	User     string `json:"user"`
 	DBName   string `json:"dbname"`
 	SSLMode  string `json:"sslmode"`
 	Password string `json:"password"`
+
+	MaxOpenConns            int `json:"max_open_conns"`
+	MaxIdleConns            int `json:"max_idle_conns"`
+	ConnMaxLifetimeSeconds  int `json:"conn_max_lifetime_seconds"`
+	ConnMaxIdleTimeSeconds  int `json:"conn_max_idle_time_seconds"`
+	StatementTimeoutSeconds int `json:"statement_timeout_seconds"`
+
+	ConnectRetries             int `json:"connect_retries"`
+	ConnectRetryBackoffSeconds int `json:"connect_retry_backoff_seconds"`
 }
 
 type MovieAPIHeaders struct {
@@ -20,33 +38,201 @@ type MovieAPIHeaders struct {
 	RapidAPIKey  string `json:"X-RapidAPI-Key"`
 }
 
+// CircuitBreakerConfig controls when calls to the movie provider are cut
+// off: after FailureThreshold consecutive failures, for OpenSeconds.
+type CircuitBreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold"`
+	OpenSeconds      int `json:"open_seconds"`
+}
+
+// MovieCacheConfig controls how long provider responses are reused. A zero
+// TTLSeconds disables the cache.
+type MovieCacheConfig struct {
+	TTLSeconds int `json:"ttl_seconds"`
+	MaxEntries int `json:"max_entries"`
+}
+
+// MovieQuotaConfig is the budget of paid calls to the movie provider per
+// UTC day and calendar month; zero leaves a window unbudgeted. Once
+// CacheOnlyPercent of a budget is spent, or the provider reports as little
+// left of its own limit, no further calls are made and only cached responses
+// are served until the window resets.
+type MovieQuotaConfig struct {
+	DailyRequests    int `json:"daily_requests"`
+	MonthlyRequests  int `json:"monthly_requests"`
+	CacheOnlyPercent int `json:"cache_only_percent"`
+}
+
+// MovieTransportConfig tunes the connections to the movie provider. Idle
+// connections are kept alive for reuse; MaxConnsPerHost caps how many are
+// open at once, queueing further requests, and zero leaves it unlimited.
+// TimeoutSeconds bounds each call, including reading the response.
+type MovieTransportConfig struct {
+	TimeoutSeconds         int `json:"timeout_seconds"`
+	MaxIdleConnsPerHost    int `json:"max_idle_conns_per_host"`
+	MaxConnsPerHost        int `json:"max_conns_per_host"`
+	IdleConnTimeoutSeconds int `json:"idle_conn_timeout_seconds"`
+}
+
 type MovieAPIConfig struct {
-	BaseURL string          `json:"base_url"`
-	Headers MovieAPIHeaders `json:"headers"`
+	BaseURL        string               `json:"base_url"`
+	Headers        MovieAPIHeaders      `json:"headers"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+	Cache          MovieCacheConfig     `json:"cache"`
+	Quota          MovieQuotaConfig     `json:"quota"`
+	Transport      MovieTransportConfig `json:"transport"`
+}
+
+type LoginProtectionConfig struct {
+	FreeAttempts         int `json:"free_attempts"`
+	MaxAccountFailures   int `json:"max_account_failures"`
+	MaxIPFailures        int `json:"max_ip_failures"`
+	BaseDelaySeconds     int `json:"base_delay_seconds"`
+	MaxDelaySeconds      int `json:"max_delay_seconds"`
+	LockoutSeconds       int `json:"lockout_seconds"`
+	FailureWindowSeconds int `json:"failure_window_seconds"`
+}
+
+type MFAConfig struct {
+	Issuer        string   `json:"issuer"`
+	RequiredRoles []string `json:"required_roles"`
+}
+
+type PasswordHashingConfig struct {
+	Algorithm         string `json:"algorithm"`
+	BcryptCost        int    `json:"bcrypt_cost"`
+	Argon2MemoryKiB   uint32 `json:"argon2_memory_kib"`
+	Argon2Iterations  uint32 `json:"argon2_iterations"`
+	Argon2Parallelism uint8  `json:"argon2_parallelism"`
+}
+
+type PasswordPolicyConfig struct {
+	MinLength            int  `json:"min_length"`
+	RequireUpper         bool `json:"require_upper"`
+	RequireLower         bool `json:"require_lower"`
+	RequireDigit         bool `json:"require_digit"`
+	RequireSymbol        bool `json:"require_symbol"`
+	DisallowIdentity     bool `json:"disallow_identity"`
+	RejectCommon         bool `json:"reject_common"`
+	ResetTokenTTLMinutes int  `json:"reset_token_ttl_minutes"`
+}
+
+type OIDCProviderConfig struct {
+	IssuerURL    string   `json:"issuer_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+}
+
+type SessionConfig struct {
+	RefreshTokenTTLHours   int `json:"refresh_token_ttl_hours"`
+	CleanupIntervalMinutes int `json:"cleanup_interval_minutes"`
+}
+
+// SecurityConfig holds the authentication settings. JWTSecret signs access,
+// MFA and OIDC state tokens; it has no default and must be at least 32 bytes,
+// so set it through MOVIERENTAL_SECURITY_JWT_SECRET rather than the file.
+type SecurityConfig struct {
+	JWTSecret       string                        `json:"jwt_secret"`
+	Login           LoginProtectionConfig         `json:"login"`
+	MFA             MFAConfig                     `json:"mfa"`
+	PasswordHashing PasswordHashingConfig         `json:"password_hashing"`
+	PasswordPolicy  PasswordPolicyConfig          `json:"password_policy"`
+	OIDCProviders   map[string]OIDCProviderConfig `json:"oidc_providers"`
+	Sessions        SessionConfig                 `json:"sessions"`
+}
+
+// RequestTimeoutConfig bounds how long a request may run, including the
+// database queries and upstream calls made for it. Routes overrides
+// DefaultSeconds for individual routes, keyed by method and path as
+// registered on the router, e.g. "GET /listallmovies".
+type RequestTimeoutConfig struct {
+	DefaultSeconds int            `json:"default_seconds"`
+	Routes         map[string]int `json:"routes"`
+}
+
+// RateLimitRule allows RequestsPerMinute requests per client on average,
+// with bursts of up to Burst requests. A rate of zero disables the limit.
+type RateLimitRule struct {
+	RequestsPerMinute int `json:"requests_per_minute"`
+	Burst             int `json:"burst"`
+}
+
+// RateLimitConfig limits how fast one client may call each group of routes:
+// "auth" for sign-up, login and token endpoints, keyed by client IP, and
+// "movies", "cart", "account" and "admin" for authenticated routes, keyed by
+// user. Groups without a rule are not limited.
+type RateLimitConfig struct {
+	Enabled bool                     `json:"enabled"`
+	Groups  map[string]RateLimitRule `json:"groups"`
+}
+
+// ServerConfig tunes the HTTP server. ShutdownTimeoutSeconds is how long
+// in-flight requests may take to finish once the process is asked to stop.
+// TrustedProxies lists the IPs or CIDR ranges of the reverse proxies in front
+// of the server; only their X-Forwarded-For and X-Real-IP headers are
+// believed. With none, the client IP is always the connection's peer.
+type ServerConfig struct {
+	ReadTimeoutSeconds     int      `json:"read_timeout_seconds"`
+	WriteTimeoutSeconds    int      `json:"write_timeout_seconds"`
+	IdleTimeoutSeconds     int      `json:"idle_timeout_seconds"`
+	MaxHeaderBytes         int      `json:"max_header_bytes"`
+	ShutdownTimeoutSeconds int      `json:"shutdown_timeout_seconds"`
+	TrustedProxies         []string `json:"trusted_proxies"`
+}
+
+// MetricsConfig controls the Prometheus endpoint. Metrics are served at
+// /metrics on a listener of their own, Address, so they stay off the public
+// API port; an empty Address turns them off. BearerToken, when set, must be
+// sent by the scraper as "Authorization: Bearer <token>".
+type MetricsConfig struct {
+	Address     string `json:"address"`
+	BearerToken string `json:"bearer_token"`
+}
+
+// TracingConfig controls OpenTelemetry tracing, which is off by default.
+// Spans go to OTLPEndpoint, or the collector named by the standard
+// OTEL_EXPORTER_OTLP_* variables, and are written to stderr when neither is
+// set. SampleRatio is the fraction of new traces recorded; zero records all
+// of them.
+type TracingConfig struct {
+	Enabled      bool    `json:"enabled"`
+	ServiceName  string  `json:"service_name"`
+	OTLPEndpoint string  `json:"otlp_endpoint"`
+	SampleRatio  float64 `json:"sample_ratio"`
+}
+
+// LoggingConfig sets the minimum level logged: debug, info, warn or error.
+type LoggingConfig struct {
+	Level string `json:"level"`
 }
 
 type Config struct {
 	Port        string         `json:"port"`
 	Environment string         `json:"environment"`
 	GinMode     string         `json:"gin_mode"`
+	Server      ServerConfig   `json:"server"`
 	Database    DatabaseConfig `json:"database"`
 	MovieAPI    MovieAPIConfig `json:"movie_api"`
+	Security    SecurityConfig `json:"security"`
+
+	RequestTimeouts RequestTimeoutConfig `json:"request_timeouts"`
+	RateLimits      RateLimitConfig      `json:"rate_limits"`
+	Metrics         MetricsConfig        `json:"metrics"`
+	Tracing         TracingConfig        `json:"tracing"`
+	Logging         LoggingConfig        `json:"logging"`
 }
 
 var AppConfig *Config
 
-func LoadConfig() error {
-	file, err := os.Open("config/config.json")
+// LoadConfig loads the configuration from path, or from the default location
+// when path is empty, and makes it available as AppConfig.
+func LoadConfig(path string) error {
+	cfg, err := Load(path, os.LookupEnv)
 	if err != nil {
-		return fmt.Errorf("failed to open config file: %w", err)
+		return err
 	}
-	defer file.Close()
-
-	cfg := &Config{}
-	if err := json.NewDecoder(file).Decode(cfg); err != nil {
-		return fmt.Errorf("failed to decode config JSON: %w", err)
-	}
-
 	AppConfig = cfg
 	return nil
 }
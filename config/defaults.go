@@ -0,0 +1,100 @@
+package config
+
+// Defaults returns the configuration used for every field the config file
+// and environment leave unset. Connection details and credentials have no
+// defaults.
+func Defaults() *Config {
+	return &Config{
+		Port:        "8080",
+		Environment: "development",
+		GinMode:     "release",
+		Server: ServerConfig{
+			ReadTimeoutSeconds:     15,
+			WriteTimeoutSeconds:    30,
+			IdleTimeoutSeconds:     60,
+			MaxHeaderBytes:         1 << 20,
+			ShutdownTimeoutSeconds: 20,
+		},
+		Database: DatabaseConfig{
+			Driver:                     "postgres",
+			Path:                       "movierental.db",
+			Host:                       "localhost",
+			Port:                       5432,
+			SSLMode:                    "disable",
+			MaxOpenConns:               25,
+			MaxIdleConns:               10,
+			ConnMaxLifetimeSeconds:     1800,
+			ConnMaxIdleTimeSeconds:     300,
+			StatementTimeoutSeconds:    30,
+			ConnectRetries:             5,
+			ConnectRetryBackoffSeconds: 1,
+		},
+		MovieAPI: MovieAPIConfig{
+			BaseURL: "https://movie-database-api1.p.rapidapi.com",
+			Headers: MovieAPIHeaders{
+				RapidAPIHost: "movie-database-api1.p.rapidapi.com",
+			},
+			CircuitBreaker: CircuitBreakerConfig{FailureThreshold: 5, OpenSeconds: 30},
+			Cache:          MovieCacheConfig{TTLSeconds: 300, MaxEntries: 1000},
+			Quota:          MovieQuotaConfig{CacheOnlyPercent: 95},
+			Transport: MovieTransportConfig{
+				TimeoutSeconds:         10,
+				MaxIdleConnsPerHost:    32,
+				MaxConnsPerHost:        64,
+				IdleConnTimeoutSeconds: 90,
+			},
+		},
+		Security: SecurityConfig{
+			Login: LoginProtectionConfig{
+				FreeAttempts:         3,
+				MaxAccountFailures:   10,
+				MaxIPFailures:        50,
+				BaseDelaySeconds:     1,
+				MaxDelaySeconds:      60,
+				LockoutSeconds:       900,
+				FailureWindowSeconds: 900,
+			},
+			MFA: MFAConfig{
+				Issuer:        "Movie Rental",
+				RequiredRoles: []string{"staff", "admin"},
+			},
+			PasswordHashing: PasswordHashingConfig{
+				Algorithm:         "argon2id",
+				BcryptCost:        10,
+				Argon2MemoryKiB:   19456,
+				Argon2Iterations:  2,
+				Argon2Parallelism: 1,
+			},
+			PasswordPolicy: PasswordPolicyConfig{
+				MinLength:            10,
+				RequireUpper:         true,
+				RequireLower:         true,
+				RequireDigit:         true,
+				DisallowIdentity:     true,
+				RejectCommon:         true,
+				ResetTokenTTLMinutes: 60,
+			},
+			Sessions: SessionConfig{
+				RefreshTokenTTLHours:   720,
+				CleanupIntervalMinutes: 60,
+			},
+		},
+		RequestTimeouts: RequestTimeoutConfig{DefaultSeconds: 10},
+		RateLimits: RateLimitConfig{
+			Enabled: true,
+			Groups: map[string]RateLimitRule{
+				"auth":    {RequestsPerMinute: 10, Burst: 5},
+				"movies":  {RequestsPerMinute: 30, Burst: 10},
+				"cart":    {RequestsPerMinute: 120, Burst: 30},
+				"account": {RequestsPerMinute: 30, Burst: 10},
+				"admin":   {RequestsPerMinute: 60, Burst: 20},
+			},
+		},
+		Metrics: MetricsConfig{Address: "127.0.0.1:9090"},
+		Tracing: TracingConfig{
+			ServiceName: "movierental",
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{Level: "info"},
+	}
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every variable that overrides a config field.
+const EnvPrefix = "MOVIERENTAL"
+
+// applyEnv overrides fields of cfg from variables named after their JSON
+// keys, e.g. database.password is read from MOVIERENTAL_DATABASE_PASSWORD and
+// movie_api.headers.X-RapidAPI-Key from
+// MOVIERENTAL_MOVIE_API_HEADERS_X_RAPIDAPI_KEY. Lists take comma-separated
+// values or JSON, and maps take JSON. Entries already present in a map of
+// objects, such as an OIDC provider, can also be overridden field by field:
+// MOVIERENTAL_SECURITY_OIDC_PROVIDERS_GOOGLE_CLIENT_SECRET. It returns a
+// problem for every variable that could not be parsed.
+func applyEnv(cfg *Config, lookupEnv func(string) (string, bool)) []string {
+	var problems []string
+	overrideStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix, lookupEnv, &problems)
+	return problems
+}
+
+func overrideStruct(v reflect.Value, prefix string, lookupEnv func(string) (string, bool), problems *[]string) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		overrideValue(v.Field(i), envName(prefix, name), lookupEnv, problems)
+	}
+}
+
+func overrideValue(v reflect.Value, name string, lookupEnv func(string) (string, bool), problems *[]string) {
+	if v.Kind() == reflect.Struct {
+		overrideStruct(v, name, lookupEnv, problems)
+		return
+	}
+	if raw, ok := lookupEnv(name); ok {
+		if err := setFromString(v, raw); err != nil {
+			*problems = append(*problems, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Struct {
+		for _, key := range v.MapKeys() {
+			entry := reflect.New(v.Type().Elem()).Elem()
+			entry.Set(v.MapIndex(key))
+			overrideStruct(entry, envName(name, key.String()), lookupEnv, problems)
+			v.SetMapIndex(key, entry)
+		}
+	}
+}
+
+func setFromString(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("expected true or false, got %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", raw)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer, got %q", raw)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(raw), "[") {
+			items := []string{}
+			for _, item := range strings.Split(raw, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+			v.Set(reflect.ValueOf(items))
+			return nil
+		}
+		return setFromJSON(v, raw)
+	case reflect.Map:
+		return setFromJSON(v, raw)
+	default:
+		return fmt.Errorf("cannot be set from the environment")
+	}
+	return nil
+}
+
+func setFromJSON(v reflect.Value, raw string) error {
+	decoded := reflect.New(v.Type())
+	if err := json.Unmarshal([]byte(raw), decoded.Interface()); err != nil {
+		return fmt.Errorf("expected JSON: %v", err)
+	}
+	v.Set(decoded.Elem())
+	return nil
+}
+
+// envName appends key to prefix, upper-cased with every character other than
+// a letter or digit replaced by an underscore.
+func envName(prefix string, key string) string {
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteByte('_')
+	for _, r := range strings.ToUpper(key) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
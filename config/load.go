@@ -0,0 +1,114 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// PathEnv names the config file when no path is given explicitly.
+	PathEnv     = "MOVIERENTAL_CONFIG"
+	defaultPath = "config/config.json"
+)
+
+// Load builds the configuration from the defaults, the file at path and the
+// MOVIERENTAL_* variables returned by lookupEnv, in increasing order of
+// precedence, and validates the result. The file may be JSON, YAML or TOML,
+// chosen by its extension. Every problem found is reported in a single
+// *ValidationError.
+func Load(path string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	path, err := resolvePath(path, lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := Defaults()
+	if err := decodeFile(path, cfg); err != nil {
+		return nil, err
+	}
+
+	problems := applyEnv(cfg, lookupEnv)
+	problems = append(problems, cfg.Validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+	return cfg, nil
+}
+
+// resolvePath returns path, the file named by MOVIERENTAL_CONFIG, or the
+// default config/config.json looked up first in the working directory and
+// then next to the executable.
+func resolvePath(path string, lookupEnv func(string) (string, bool)) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if envPath, ok := lookupEnv(PathEnv); ok && envPath != "" {
+		return envPath, nil
+	}
+
+	candidates := []string{defaultPath}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), defaultPath))
+	}
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no config file found at %s; pass --config or set %s", strings.Join(candidates, " or "), PathEnv)
+}
+
+// decodeFile reads the file at path into cfg. YAML and TOML are converted to
+// JSON first, so the json tags on Config name the keys in every format.
+func decodeFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return fmt.Errorf("failed to convert YAML config %s: %w", path, err)
+		}
+	case ".toml":
+		var values map[string]interface{}
+		if err := toml.Unmarshal(data, &values); err != nil {
+			return fmt.Errorf("failed to parse TOML config %s: %w", path, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return fmt.Errorf("failed to convert TOML config %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q; use .json, .yaml, .yml or .toml", ext)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to decode config %s: %w", path, err)
+	}
+	return nil
+}
+
+// ValidationError lists everything wrong with a configuration.
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid configuration in %s:\n  - %s", e.Path, strings.Join(e.Problems, "\n  - "))
+}
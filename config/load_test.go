@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name string, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+const testJWTSecret = "a-test-signing-key-of-32-bytes-min"
+
+func envFrom(values map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := values[name]
+		return value, ok
+	}
+}
+
+func TestLoad_FormatsAndEnvOverrides(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"database": {"user": "app", "dbname": "movies"}, "security": {"oidc_providers": {"google": {"issuer_url": "https://accounts.google.com", "client_id": "id", "redirect_url": "http://localhost/cb"}}}}`,
+		"config.yaml": "database:\n  user: app\n  dbname: movies\nsecurity:\n  oidc_providers:\n    google:\n      issuer_url: https://accounts.google.com\n      client_id: id\n      redirect_url: http://localhost/cb\n",
+		"config.toml": "[database]\nuser = \"app\"\ndbname = \"movies\"\n[security.oidc_providers.google]\nissuer_url = \"https://accounts.google.com\"\nclient_id = \"id\"\nredirect_url = \"http://localhost/cb\"\n",
+	}
+	env := envFrom(map[string]string{
+		"MOVIERENTAL_DATABASE_PASSWORD":                            "s3cret",
+		"MOVIERENTAL_MOVIE_API_HEADERS_X_RAPIDAPI_KEY":             "rapid",
+		"MOVIERENTAL_SECURITY_JWT_SECRET":                          testJWTSecret,
+		"MOVIERENTAL_SECURITY_MFA_REQUIRED_ROLES":                  "admin, staff",
+		"MOVIERENTAL_TRACING_ENABLED":                              "true",
+		"MOVIERENTAL_SECURITY_OIDC_PROVIDERS_GOOGLE_CLIENT_SECRET": "oidc-secret",
+		"MOVIERENTAL_REQUEST_TIMEOUTS_ROUTES":                      `{"GET /movie": 5}`,
+	})
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, name, content), env)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if cfg.Database.User != "app" || cfg.Database.Password != "s3cret" {
+				t.Errorf("Expected file and env values for the database, got %+v", cfg.Database)
+			}
+			if cfg.Database.Port != 5432 || cfg.Port != "8080" {
+				t.Errorf("Expected defaults for unset fields, got database port %d and port %q", cfg.Database.Port, cfg.Port)
+			}
+			if cfg.MovieAPI.Headers.RapidAPIKey != "rapid" || !cfg.Tracing.Enabled {
+				t.Error("Expected env overrides to apply")
+			}
+			if got := cfg.Security.MFA.RequiredRoles; len(got) != 2 || got[0] != "admin" || got[1] != "staff" {
+				t.Errorf("Expected a comma-separated list, got %v", got)
+			}
+			if cfg.Security.OIDCProviders["google"].ClientSecret != "oidc-secret" {
+				t.Errorf("Expected a per-provider override, got %+v", cfg.Security.OIDCProviders["google"])
+			}
+			if cfg.RequestTimeouts.Routes["GET /movie"] != 5 {
+				t.Errorf("Expected a JSON map override, got %v", cfg.RequestTimeouts.Routes)
+			}
+		})
+	}
+}
+
+func TestLoad_ReportsEveryProblem(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"gin_mode": "loud", "database": {"port": 0}, "tracing": {"sample_ratio": 2}, "server": {"trusted_proxies": ["10.0.0.0/8", "proxy"]}, "metrics": {"address": "9090"}, "security": {"jwt_secret": "secret", "password_hashing": {"algorithm": "argon2id", "argon2_memory_kib": 64, "argon2_iterations": 0, "argon2_parallelism": 0}}}`)
+
+	_, err := Load(path, envFrom(map[string]string{"MOVIERENTAL_SERVER_IDLE_TIMEOUT_SECONDS": "soon"}))
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a ValidationError, got: %v", err)
+	}
+	for _, want := range []string{
+		"MOVIERENTAL_SERVER_IDLE_TIMEOUT_SECONDS",
+		"gin_mode",
+		"database.user is required",
+		"database.dbname is required",
+		"database.port",
+		"movie_api.headers.X-RapidAPI-Key is required",
+		"tracing.sample_ratio",
+		`server.trusted_proxies must hold IP addresses or CIDR ranges, got "proxy"`,
+		"metrics.address",
+		"security.jwt_secret must be at least 32 bytes",
+		"security.password_hashing.argon2_memory_kib",
+		"security.password_hashing.argon2_iterations",
+		"security.password_hashing.argon2_parallelism",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %q, got:\n%v", want, err)
+		}
+	}
+}
+
+func TestLoad_RejectsUnknownFields(t *testing.T) {
+	path := writeConfig(t, "config.json", `{"databse": {}}`)
+	if _, err := Load(path, envFrom(nil)); err == nil || !strings.Contains(err.Error(), "databse") {
+		t.Errorf("Expected the unknown field to be reported, got: %v", err)
+	}
+}
+
+func TestLoad_UsesPathFromEnv(t *testing.T) {
+	path := writeConfig(t, "app.yml", "database:\n  user: app\n  dbname: movies\nmovie_api:\n  headers:\n    X-RapidAPI-Key: key\n")
+	cfg, err := Load("", envFrom(map[string]string{PathEnv: path, "MOVIERENTAL_SECURITY_JWT_SECRET": testJWTSecret}))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if cfg.Database.DBName != "movies" {
+		t.Errorf("Expected the file named by %s to be loaded, got %+v", PathEnv, cfg.Database)
+	}
+}
+
+func TestLoad_CommittedConfigIsValidWithKey(t *testing.T) {
+	env := envFrom(map[string]string{
+		"MOVIERENTAL_MOVIE_API_HEADERS_X_RAPIDAPI_KEY": "key",
+		"MOVIERENTAL_SECURITY_JWT_SECRET":              testJWTSecret,
+	})
+	if _, err := Load("config.json", env); err != nil {
+		t.Errorf("Expected the committed config to be valid, got: %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Validate returns every problem with cfg, each naming the field by its JSON
+// path. An empty result means cfg is usable.
+func (cfg *Config) Validate() []string {
+	v := &validator{}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		v.addf("port must be a number between 1 and 65535, got %q", cfg.Port)
+	}
+	v.required("environment", cfg.Environment)
+	v.oneOf("gin_mode", cfg.GinMode, "debug", "release", "test")
+
+	v.nonNegative("server.read_timeout_seconds", cfg.Server.ReadTimeoutSeconds)
+	v.nonNegative("server.write_timeout_seconds", cfg.Server.WriteTimeoutSeconds)
+	v.nonNegative("server.idle_timeout_seconds", cfg.Server.IdleTimeoutSeconds)
+	v.nonNegative("server.max_header_bytes", cfg.Server.MaxHeaderBytes)
+	v.nonNegative("server.shutdown_timeout_seconds", cfg.Server.ShutdownTimeoutSeconds)
+	if cfg.Metrics.Address != "" {
+		if _, port, err := net.SplitHostPort(cfg.Metrics.Address); err != nil || port == "" {
+			v.addf("metrics.address must be host:port or :port, got %q", cfg.Metrics.Address)
+		} else if port == cfg.Port {
+			v.addf("metrics.address must not use the API port %s", cfg.Port)
+		}
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			v.addf("server.trusted_proxies must hold IP addresses or CIDR ranges, got %q", proxy)
+		}
+	}
+
+	db := cfg.Database
+	v.oneOf("database.driver", db.Driver, "postgres", "sqlite")
+	if db.Driver == "sqlite" {
+		v.required("database.path", db.Path)
+	}
+	if db.Driver == "postgres" && db.URL == "" {
+		v.required("database.host", db.Host)
+		v.required("database.user", db.User)
+		v.required("database.dbname", db.DBName)
+		if db.Port < 1 || db.Port > 65535 {
+			v.addf("database.port must be between 1 and 65535, got %d", db.Port)
+		}
+		v.oneOf("database.sslmode", db.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full")
+	}
+	v.nonNegative("database.max_open_conns", db.MaxOpenConns)
+	v.nonNegative("database.max_idle_conns", db.MaxIdleConns)
+	if db.MaxOpenConns > 0 && db.MaxIdleConns > db.MaxOpenConns {
+		v.addf("database.max_idle_conns (%d) must not exceed database.max_open_conns (%d)", db.MaxIdleConns, db.MaxOpenConns)
+	}
+	v.nonNegative("database.conn_max_lifetime_seconds", db.ConnMaxLifetimeSeconds)
+	v.nonNegative("database.conn_max_idle_time_seconds", db.ConnMaxIdleTimeSeconds)
+	v.nonNegative("database.statement_timeout_seconds", db.StatementTimeoutSeconds)
+	v.nonNegative("database.connect_retries", db.ConnectRetries)
+	v.nonNegative("database.connect_retry_backoff_seconds", db.ConnectRetryBackoffSeconds)
+
+	v.absoluteURL("movie_api.base_url", cfg.MovieAPI.BaseURL)
+	v.required("movie_api.headers.X-RapidAPI-Host", cfg.MovieAPI.Headers.RapidAPIHost)
+	v.required("movie_api.headers.X-RapidAPI-Key", cfg.MovieAPI.Headers.RapidAPIKey)
+	v.nonNegative("movie_api.circuit_breaker.failure_threshold", cfg.MovieAPI.CircuitBreaker.FailureThreshold)
+	v.nonNegative("movie_api.circuit_breaker.open_seconds", cfg.MovieAPI.CircuitBreaker.OpenSeconds)
+	v.nonNegative("movie_api.cache.ttl_seconds", cfg.MovieAPI.Cache.TTLSeconds)
+	v.nonNegative("movie_api.cache.max_entries", cfg.MovieAPI.Cache.MaxEntries)
+	v.nonNegative("movie_api.quota.daily_requests", cfg.MovieAPI.Quota.DailyRequests)
+	v.nonNegative("movie_api.quota.monthly_requests", cfg.MovieAPI.Quota.MonthlyRequests)
+	transport := cfg.MovieAPI.Transport
+	v.nonNegative("movie_api.transport.timeout_seconds", transport.TimeoutSeconds)
+	v.nonNegative("movie_api.transport.max_idle_conns_per_host", transport.MaxIdleConnsPerHost)
+	v.nonNegative("movie_api.transport.max_conns_per_host", transport.MaxConnsPerHost)
+	v.nonNegative("movie_api.transport.idle_conn_timeout_seconds", transport.IdleConnTimeoutSeconds)
+	if percent := cfg.MovieAPI.Quota.CacheOnlyPercent; percent < 1 || percent > 100 {
+		v.addf("movie_api.quota.cache_only_percent must be between 1 and 100, got %d", percent)
+	}
+
+	if secret := cfg.Security.JWTSecret; strings.TrimSpace(secret) == "" {
+		v.addf("security.jwt_secret is required")
+	} else if len(secret) < 32 {
+		v.addf("security.jwt_secret must be at least 32 bytes, got %d", len(secret))
+	}
+
+	login := cfg.Security.Login
+	v.nonNegative("security.login.free_attempts", login.FreeAttempts)
+	v.nonNegative("security.login.max_account_failures", login.MaxAccountFailures)
+	v.nonNegative("security.login.max_ip_failures", login.MaxIPFailures)
+	v.nonNegative("security.login.base_delay_seconds", login.BaseDelaySeconds)
+	v.nonNegative("security.login.max_delay_seconds", login.MaxDelaySeconds)
+	v.nonNegative("security.login.lockout_seconds", login.LockoutSeconds)
+	v.nonNegative("security.login.failure_window_seconds", login.FailureWindowSeconds)
+
+	for _, role := range cfg.Security.MFA.RequiredRoles {
+		v.oneOf("security.mfa.required_roles", role, "user", "staff", "admin")
+	}
+
+	hashing := cfg.Security.PasswordHashing
+	v.oneOf("security.password_hashing.algorithm", hashing.Algorithm, "argon2id", "bcrypt")
+	if hashing.Algorithm == "bcrypt" && (hashing.BcryptCost < 4 || hashing.BcryptCost > 31) {
+		v.addf("security.password_hashing.bcrypt_cost must be between 4 and 31, got %d", hashing.BcryptCost)
+	}
+	if hashing.Algorithm == "argon2id" {
+		// The lower bounds follow the weakest argon2id setting OWASP still
+		// recommends; the upper ones catch values mistaken for other units.
+		if hashing.Argon2MemoryKiB < 7168 || hashing.Argon2MemoryKiB > 4194304 {
+			v.addf("security.password_hashing.argon2_memory_kib must be between 7168 (7 MiB) and 4194304 (4 GiB), got %d", hashing.Argon2MemoryKiB)
+		}
+		if hashing.Argon2Iterations < 1 || hashing.Argon2Iterations > 100 {
+			v.addf("security.password_hashing.argon2_iterations must be between 1 and 100, got %d", hashing.Argon2Iterations)
+		}
+		if hashing.Argon2Parallelism < 1 || hashing.Argon2Parallelism > 64 {
+			v.addf("security.password_hashing.argon2_parallelism must be between 1 and 64, got %d", hashing.Argon2Parallelism)
+		}
+	}
+
+	if cfg.Security.PasswordPolicy.MinLength < 1 {
+		v.addf("security.password_policy.min_length must be at least 1, got %d", cfg.Security.PasswordPolicy.MinLength)
+	}
+	v.nonNegative("security.password_policy.reset_token_ttl_minutes", cfg.Security.PasswordPolicy.ResetTokenTTLMinutes)
+
+	providers := make([]string, 0, len(cfg.Security.OIDCProviders))
+	for name := range cfg.Security.OIDCProviders {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	for _, name := range providers {
+		provider := cfg.Security.OIDCProviders[name]
+		prefix := "security.oidc_providers." + name
+		v.absoluteURL(prefix+".issuer_url", provider.IssuerURL)
+		v.required(prefix+".client_id", provider.ClientID)
+		v.absoluteURL(prefix+".redirect_url", provider.RedirectURL)
+	}
+
+	v.nonNegative("security.sessions.refresh_token_ttl_hours", cfg.Security.Sessions.RefreshTokenTTLHours)
+	v.nonNegative("security.sessions.cleanup_interval_minutes", cfg.Security.Sessions.CleanupIntervalMinutes)
+
+	v.nonNegative("request_timeouts.default_seconds", cfg.RequestTimeouts.DefaultSeconds)
+	routes := make([]string, 0, len(cfg.RequestTimeouts.Routes))
+	for route := range cfg.RequestTimeouts.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		v.nonNegative(fmt.Sprintf("request_timeouts.routes[%q]", route), cfg.RequestTimeouts.Routes[route])
+	}
+
+	groups := make([]string, 0, len(cfg.RateLimits.Groups))
+	for group := range cfg.RateLimits.Groups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		rule := cfg.RateLimits.Groups[group]
+		v.oneOf("rate_limits.groups", group, "auth", "movies", "cart", "account", "admin")
+		v.nonNegative("rate_limits.groups."+group+".requests_per_minute", rule.RequestsPerMinute)
+		if rule.RequestsPerMinute > 0 && rule.Burst < 1 {
+			v.addf("rate_limits.groups.%s.burst must be at least 1, got %d", group, rule.Burst)
+		}
+	}
+
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio must be between 0 and 1, got %g", cfg.Tracing.SampleRatio)
+	}
+	if cfg.Tracing.OTLPEndpoint != "" {
+		v.absoluteURL("tracing.otlp_endpoint", cfg.Tracing.OTLPEndpoint)
+	}
+
+	v.oneOf("logging.level", strings.ToLower(cfg.Logging.Level), "debug", "info", "warn", "warning", "error")
+
+	return v.problems
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(field string, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.addf("%s is required", field)
+	}
+}
+
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.addf("%s must not be negative, got %d", field, value)
+	}
+}
+
+func (v *validator) oneOf(field string, value string, allowed ...string) {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.addf("%s must be one of %s, got %q", field, strings.Join(allowed, ", "), value)
+}
+
+func (v *validator) absoluteURL(field string, value string) {
+	if value == "" {
+		v.addf("%s is required", field)
+		return
+	}
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		v.addf("%s must be an absolute URL, got %q", field, value)
+	}
+}
@@ -1,15 +1,143 @@
+// Command migrate manages the database schema:
+//
+//	go run ./migrate [--config path] up            apply every pending migration
+//	go run ./migrate [--config path] down [n]      roll back the last n migrations (default 1)
+//	go run ./migrate [--config path] status        list migrations and when they were applied
+//	go run ./migrate [--dir path] create <name>    write an empty migration
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"movierental/config"
 	"movierental/pkg/database"
-	"movierental/pkg/models"
-	"movierental/pkg/models/requests"
+	"movierental/pkg/database/migrations"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
 )
 
-func init() {
-	database.ConnectToDb()
-}
+const usage = `usage: migrate [flags] <command>
+
+commands:
+  up            apply every pending migration
+  down [n]      roll back the last n applied migrations (default 1)
+  status        list migrations and when they were applied
+  create <name> write an empty migration into --dir
+
+flags:
+`
 
 func main() {
-	database.DB.AutoMigrate(&models.User{}, &requests.Cart{})
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the config file (JSON, YAML or TOML); defaults to $"+config.PathEnv+" or config/config.json")
+	dir := flags.String("dir", "pkg/database/migrations", "directory new migrations are created in")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+
+	if err := run(context.Background(), flags.Args(), *configPath, *dir, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("missing or unknown command")
+
+func run(ctx context.Context, args []string, configPath string, dir string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	command, args := args[0], args[1:]
+
+	if command == "create" {
+		if len(args) != 1 {
+			return errUsage
+		}
+		path, err := migrations.Create(dir, args[0], time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Created", path)
+		return nil
+	}
+
+	var operation func(context.Context, *gorm.DB) error
+	switch command {
+	case "up":
+		operation = func(ctx context.Context, db *gorm.DB) error {
+			applied, err := migrations.Up(ctx, db)
+			for _, m := range applied {
+				fmt.Fprintln(out, "Applied", m)
+			}
+			if err == nil && len(applied) == 0 {
+				fmt.Fprintln(out, "Already up to date")
+			}
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 0 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("down takes a positive number of migrations, got %q", args[0])
+			}
+			steps = n
+		}
+		operation = func(ctx context.Context, db *gorm.DB) error {
+			rolledBack, err := migrations.Down(ctx, db, steps)
+			for _, m := range rolledBack {
+				fmt.Fprintln(out, "Rolled back", m)
+			}
+			if err == nil && len(rolledBack) == 0 {
+				fmt.Fprintln(out, "Nothing to roll back")
+			}
+			return err
+		}
+	case "status":
+		operation = func(ctx context.Context, db *gorm.DB) error {
+			statuses, err := migrations.List(ctx, db)
+			if err != nil {
+				return err
+			}
+			for _, status := range statuses {
+				state := "pending"
+				if status.AppliedAt != nil {
+					state = "applied " + status.AppliedAt.UTC().Format(time.RFC3339)
+				}
+				if status.Unknown {
+					state += " (not in this build)"
+				}
+				fmt.Fprintf(out, "%-50s %s\n", status.Migration, state)
+			}
+			return nil
+		}
+	default:
+		return errUsage
+	}
+
+	if err := config.LoadConfig(configPath); err != nil {
+		return err
+	}
+	db, err := database.Connect(ctx, config.AppConfig.Database)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}()
+	return operation(ctx, db)
 }
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyController struct {
+	APIKeyService services.APIKeyServiceInterface
+}
+
+// CreateAPIKey
+// @Summary Create an API key
+// @Description Creates an API key for the authenticated user for service-to-service or partner access, limited to the given scopes. The key is returned only once; send it in the X-API-Key header. Requires a bearer token.
+// @Tags api-keys
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body requests.CreateAPIKey true "Key name, scopes and optional lifetime in days"
+// @Success 201 {object} object{message=string,id=string,key=string,name=string,prefix=string,scopes=[]string,expires_at=string} "API key created"
+// @Failure 400 {object} object{error=string} "Bad Request: Invalid input data or scopes"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: API keys cannot manage API keys"
+// @Failure 404 {object} object{error=string} "Not Found: User not found"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/api-keys [post]
+func (akc *APIKeyController) CreateAPIKey(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+	var keyReq requests.CreateAPIKey
+	if err := c.ShouldBindJSON(&keyReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for create API key request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := akc.APIKeyService.CreateAPIKey(c.Request.Context(), userId.(string), keyReq)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error creating API key", "error", err)
+		if errors.Is(err, services.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListAPIKeys
+// @Summary List API keys
+// @Description Lists the authenticated user's API keys, including revoked and expired ones. Secrets are never returned.
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} object{api_keys=[]object{id=string,name=string,prefix=string,scopes=[]string,created_at=string,expires_at=string,last_used_at=string,revoked_at=string}} "API keys"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: API keys cannot manage API keys"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/api-keys [get]
+func (akc *APIKeyController) ListAPIKeys(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := akc.APIKeyService.ListAPIKeys(c.Request.Context(), userId.(string))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error listing API keys", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAPIKey
+// @Summary Revoke an API key
+// @Description Revokes one of the authenticated user's API keys. Requests using it are rejected immediately.
+// @Tags api-keys
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} object{message=string,id=string} "API key revoked"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: API keys cannot manage API keys"
+// @Failure 404 {object} object{error=string} "Not Found: API key not found"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/api-keys/{id} [delete]
+func (akc *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := akc.APIKeyService.RevokeAPIKey(c.Request.Context(), userId.(string), c.Param("id"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error revoking API key", "error", err)
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockAPIKeyService struct {
+	CreateAPIKeyFunc func(userId string, keyReq requests.CreateAPIKey) (map[string]interface{}, error)
+	ListAPIKeysFunc  func(userId string) (map[string]interface{}, error)
+	RevokeAPIKeyFunc func(userId string, keyId string) (map[string]interface{}, error)
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(ctx context.Context, userId string, keyReq requests.CreateAPIKey) (map[string]interface{}, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(userId, keyReq)
+	}
+	return nil, errors.New("CreateAPIKeyFunc not implemented")
+}
+
+func (m *MockAPIKeyService) ListAPIKeys(ctx context.Context, userId string) (map[string]interface{}, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(userId)
+	}
+	return nil, errors.New("ListAPIKeysFunc not implemented")
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(ctx context.Context, userId string, keyId string) (map[string]interface{}, error) {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(userId, keyId)
+	}
+	return nil, errors.New("RevokeAPIKeyFunc not implemented")
+}
+
+func setupTestRouterForAPIKeys(mockAPIKeyService *MockAPIKeyService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	apiKeyController := &APIKeyController{APIKeyService: mockAPIKeyService}
+
+	authenticated := router.Group("/")
+	authenticated.Use(func(c *gin.Context) {
+		c.Set("userId", "test-user-id")
+		c.Next()
+	})
+	authenticated.POST("/me/api-keys", apiKeyController.CreateAPIKey)
+	authenticated.GET("/me/api-keys", apiKeyController.ListAPIKeys)
+	authenticated.DELETE("/me/api-keys/:id", apiKeyController.RevokeAPIKey)
+	return router
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("successful creation", func(t *testing.T) {
+		router := setupTestRouterForAPIKeys(&MockAPIKeyService{
+			CreateAPIKeyFunc: func(userId string, keyReq requests.CreateAPIKey) (map[string]interface{}, error) {
+				if userId != "test-user-id" || keyReq.Name != "partner" || len(keyReq.Scopes) != 1 {
+					t.Errorf("Unexpected arguments: %s %+v", userId, keyReq)
+				}
+				return map[string]interface{}{"id": "key-1", "key": "mrk_0011aabb_secret"}, nil
+			},
+		})
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "partner", "scopes": []string{"movies:read"}})
+		req, _ := http.NewRequest(http.MethodPost, "/me/api-keys", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("missing name", func(t *testing.T) {
+		router := setupTestRouterForAPIKeys(&MockAPIKeyService{})
+
+		req, _ := http.NewRequest(http.MethodPost, "/me/api-keys", bytes.NewBufferString(`{"scopes":[]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	t.Run("unknown key", func(t *testing.T) {
+		router := setupTestRouterForAPIKeys(&MockAPIKeyService{
+			RevokeAPIKeyFunc: func(userId string, keyId string) (map[string]interface{}, error) {
+				return nil, services.ErrAPIKeyNotFound
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodDelete, "/me/api-keys/missing", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
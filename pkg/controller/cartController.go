@@ -2,7 +2,7 @@ package controller
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"movierental/pkg/models/requests"
 	"movierental/pkg/services"
 	"net/http"
@@ -20,6 +20,7 @@ type CartController struct {
 // @Description Fetches the contents of the authenticated user's shopping cart.
 // @Tags cart
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce application/json
 // @Success 200 {object} requests.Cart "Successfully retrieved cart"
 // @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
@@ -33,9 +34,9 @@ func (cc *CartController) RetriveCart(c *gin.Context) {
 		return
 	}
 
-	retrievedCart, err := cc.CartService.RetrieveCart(userId)
+	retrievedCart, err := cc.CartService.RetrieveCart(c.Request.Context(), userId)
 	if err != nil {
-		log.Printf("Error retrieving cart: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error retrieving cart", "error", err)
 		if err.Error() == "cart not found for user" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Cart not found for user"})
 		} else {
@@ -51,6 +52,7 @@ func (cc *CartController) RetriveCart(c *gin.Context) {
 // @Description Adds a specified movie item to the authenticated user's shopping cart.
 // @Tags cart
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Accept json
 // @Produce application/json
 // @Param movie_item body requests.CartMovieItem true "Movie item details to add to cart"
@@ -69,7 +71,7 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 	}
 	var movieItem requests.CartMovieItem
 	if err := c.ShouldBindJSON(&movieItem); err != nil {
-		log.Printf("Validation error for AddToCart request: %v", err)
+		slog.WarnContext(c.Request.Context(), "Validation error for AddToCart request", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -79,9 +81,9 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 		return
 	}
 
-	response, err := cc.CartService.AddToCart(userId, movieItem)
+	response, err := cc.CartService.AddToCart(c.Request.Context(), userId, movieItem)
 	if err != nil {
-		log.Printf("Error adding to cart: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error adding to cart", "error", err)
 		if err.Error() == fmt.Sprintf("cart for user ID '%s' not found. Please ensure the user exists and their cart is created", userId) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else if err.Error() == fmt.Sprintf("movie '%s' (ID: %d) is already in your cart", movieItem.Title, movieItem.ID) {
@@ -99,6 +101,7 @@ func (cc *CartController) AddToCart(c *gin.Context) {
 // @Description Removes a specified movie item from the authenticated user's shopping cart by its ID.
 // @Tags cart
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce application/json
 // @Param movie_id query int true "ID of the movie to remove from cart"
 // @Success 200 {object} object{message=string,cart_id=string,user_id=string,current_movies=[]requests.CartMovieItem} "Movie removed from cart successfully, returns updated cart details"
@@ -126,9 +129,9 @@ func (cc *CartController) RemoveFromCart(c *gin.Context) {
 		return
 	}
 
-	response, err := cc.CartService.RemoveFromCart(userId, movieID)
+	response, err := cc.CartService.RemoveFromCart(c.Request.Context(), userId, movieID)
 	if err != nil {
-		log.Printf("Error removing from cart: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error removing from cart", "error", err)
 		if err.Error() == fmt.Sprintf("cart for user ID '%s' not found", userId) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else if err.Error() == fmt.Sprintf("movie with ID %d not found in your cart", movieID) {
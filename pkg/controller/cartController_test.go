@@ -2,6 +2,7 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -19,21 +20,21 @@ type MockCartService struct {
 	RemoveFromCartFunc func(userId interface{}, movieID int) (map[string]interface{}, error)
 }
 
-func (m *MockCartService) RetrieveCart(userId interface{}) (requests.Cart, error) {
+func (m *MockCartService) RetrieveCart(ctx context.Context, userId interface{}) (requests.Cart, error) {
 	if m.RetrieveCartFunc != nil {
 		return m.RetrieveCartFunc(userId)
 	}
 	return requests.Cart{}, errors.New("RetrieveCartFunc not implemented")
 }
 
-func (m *MockCartService) AddToCart(userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error) {
+func (m *MockCartService) AddToCart(ctx context.Context, userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error) {
 	if m.AddToCartFunc != nil {
 		return m.AddToCartFunc(userId, movieItem)
 	}
 	return nil, errors.New("AddToCartFunc not implemented")
 }
 
-func (m *MockCartService) RemoveFromCart(userId interface{}, movieID int) (map[string]interface{}, error) {
+func (m *MockCartService) RemoveFromCart(ctx context.Context, userId interface{}, movieID int) (map[string]interface{}, error) {
 	if m.RemoveFromCartFunc != nil {
 		return m.RemoveFromCartFunc(userId, movieID)
 	}
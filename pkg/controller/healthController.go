@@ -0,0 +1,49 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"movierental/pkg/health"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadinessChecker runs the dependency checks that decide whether the
+// instance should receive traffic.
+type ReadinessChecker interface {
+	Run(ctx context.Context) (bool, map[string]health.Result)
+}
+
+type HealthController struct {
+	Checker ReadinessChecker
+}
+
+// Liveness
+// @Summary Liveness probe
+// @Description Reports that the process is running. It does not check any dependency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string} "Process is alive"
+// @Router /healthz [get]
+func (hc *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness
+// @Summary Readiness probe
+// @Description Checks database connectivity and pending migrations, with a result per dependency. The movie provider's circuit breaker is reported as "degraded" when open but does not make the instance unready.
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string,checks=map[string]health.Result} "Ready to serve traffic"
+// @Failure 503 {object} object{status=string,checks=map[string]health.Result} "One or more dependencies are unavailable"
+// @Router /readyz [get]
+func (hc *HealthController) Readiness(c *gin.Context) {
+	ready, checks := hc.Checker.Run(c.Request.Context())
+	if !ready {
+		slog.WarnContext(c.Request.Context(), "Readiness check failed", "checks", checks)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not_ready", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"movierental/pkg/health"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockReadinessChecker struct {
+	RunFunc func() (bool, map[string]health.Result)
+}
+
+func (m *MockReadinessChecker) Run(ctx context.Context) (bool, map[string]health.Result) {
+	return m.RunFunc()
+}
+
+func setupTestRouterForHealth(checker *MockReadinessChecker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	healthController := &HealthController{Checker: checker}
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+	return router
+}
+
+func TestLiveness(t *testing.T) {
+	router := setupTestRouterForHealth(&MockReadinessChecker{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("All dependencies healthy", func(t *testing.T) {
+		router := setupTestRouterForHealth(&MockReadinessChecker{
+			RunFunc: func() (bool, map[string]health.Result) {
+				return true, map[string]health.Result{"database": {Status: health.StatusOK}}
+			},
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("Failing dependency", func(t *testing.T) {
+		router := setupTestRouterForHealth(&MockReadinessChecker{
+			RunFunc: func() (bool, map[string]health.Result) {
+				return false, map[string]health.Result{
+					"database":  {Status: health.StatusOK},
+					"movie_api": {Status: health.StatusFail, Error: "movie provider circuit is open"},
+				}
+			},
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusServiceUnavailable, w.Code, w.Body.String())
+		}
+		var response struct {
+			Status string                   `json:"status"`
+			Checks map[string]health.Result `json:"checks"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response.Status != "not_ready" || response.Checks["movie_api"].Error == "" {
+			t.Errorf("Expected a per-dependency breakdown, got %+v", response)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/services"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAController struct {
+	MFAService services.MFAServiceInterface
+}
+
+// EnrollTOTP
+// @Summary Start TOTP enrollment
+// @Description Generates a new TOTP secret and recovery codes for the authenticated user. TOTP is enforced only after the enrollment is verified.
+// @Tags mfa
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} object{message=string,otpauth_uri=string,secret=string,recovery_codes=[]string} "Enrollment started"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 409 {object} object{error=string} "Conflict: TOTP already enabled"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/mfa/totp [post]
+func (mc *MFAController) EnrollTOTP(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := mc.MFAService.EnrollTOTP(c.Request.Context(), userId.(string))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error enrolling TOTP", "error", err)
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyTOTPEnrollment
+// @Summary Verify TOTP enrollment
+// @Description Confirms TOTP enrollment with a code from the authenticator app and returns a fresh access token.
+// @Tags mfa
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param code body requests.VerifyTOTP true "Current TOTP code"
+// @Success 200 {object} object{message=string,token=string} "TOTP enabled"
+// @Failure 400 {object} object{error=string} "Bad Request: Invalid input or no pending enrollment"
+// @Failure 401 {object} object{error=string} "Unauthorized: Invalid MFA code"
+// @Failure 409 {object} object{error=string} "Conflict: TOTP already enabled"
+// @Failure 429 {object} object{error=string} "Too many failed attempts, see Retry-After"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/mfa/totp/verify [post]
+func (mc *MFAController) VerifyTOTPEnrollment(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+	var verifyReq requests.VerifyTOTP
+	if err := c.ShouldBindJSON(&verifyReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for TOTP verification request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := mc.MFAService.VerifyTOTPEnrollment(c.Request.Context(), userId.(string), c.GetString("sessionId"), verifyReq.Code, clientInfo(c))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error verifying TOTP enrollment", "error", err)
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteMFALogin
+// @Summary Complete a two-step login
+// @Description Exchanges the MFA challenge token returned by /login and a TOTP or recovery code for an access token.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param mfa body requests.MFALogin true "MFA challenge token and code"
+// @Success 200 {object} object{message=string,token=string,refresh_token=string,session_id=string} "Login successful, returns JWT and refresh token"
+// @Failure 400 {object} object{error=string} "Bad Request: Invalid input data"
+// @Failure 401 {object} object{error=string} "Unauthorized: Invalid MFA code or token"
+// @Failure 429 {object} object{error=string} "Too many failed attempts, see Retry-After"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /login/mfa [post]
+func (mc *MFAController) CompleteMFALogin(c *gin.Context) {
+	var mfaReq requests.MFALogin
+	if err := c.ShouldBindJSON(&mfaReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for MFA login request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := mc.MFAService.CompleteLogin(c.Request.Context(), mfaReq.MFAToken, mfaReq.Code, clientInfo(c))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error completing MFA login", "error", err)
+		writeMFAError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func writeMFAError(c *gin.Context, err error) {
+	var throttled *services.LoginThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidMFACode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFANotPending):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrMFAAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"movierental/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockMFAService struct {
+	EnrollTOTPFunc           func(userId string) (map[string]interface{}, error)
+	VerifyTOTPEnrollmentFunc func(userId string, sessionId string, code string) (map[string]interface{}, error)
+	CompleteLoginFunc        func(mfaToken string, code string, client services.ClientInfo) (map[string]interface{}, error)
+}
+
+func (m *MockMFAService) EnrollTOTP(ctx context.Context, userId string) (map[string]interface{}, error) {
+	if m.EnrollTOTPFunc != nil {
+		return m.EnrollTOTPFunc(userId)
+	}
+	return nil, errors.New("EnrollTOTPFunc not implemented")
+}
+
+func (m *MockMFAService) VerifyTOTPEnrollment(ctx context.Context, userId string, sessionId string, code string, client services.ClientInfo) (map[string]interface{}, error) {
+	if m.VerifyTOTPEnrollmentFunc != nil {
+		return m.VerifyTOTPEnrollmentFunc(userId, sessionId, code)
+	}
+	return nil, errors.New("VerifyTOTPEnrollmentFunc not implemented")
+}
+
+func (m *MockMFAService) CompleteLogin(ctx context.Context, mfaToken string, code string, client services.ClientInfo) (map[string]interface{}, error) {
+	if m.CompleteLoginFunc != nil {
+		return m.CompleteLoginFunc(mfaToken, code, client)
+	}
+	return nil, errors.New("CompleteLoginFunc not implemented")
+}
+
+func setupTestRouterForMFA(mockMFAService *MockMFAService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	mfaController := &MFAController{MFAService: mockMFAService}
+
+	router.POST("/login/mfa", mfaController.CompleteMFALogin)
+
+	authenticated := router.Group("/")
+	authenticated.Use(func(c *gin.Context) {
+		c.Set("userId", "test-user-id")
+		c.Next()
+	})
+	authenticated.POST("/me/mfa/totp", mfaController.EnrollTOTP)
+	authenticated.POST("/me/mfa/totp/verify", mfaController.VerifyTOTPEnrollment)
+	return router
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	t.Run("successful enrollment", func(t *testing.T) {
+		mockMFAService := &MockMFAService{
+			EnrollTOTPFunc: func(userId string) (map[string]interface{}, error) {
+				return map[string]interface{}{
+					"otpauth_uri":    "otpauth://totp/Movie%20Rental:" + userId,
+					"recovery_codes": []string{"aaaaa-bbbbb"},
+				}, nil
+			},
+		}
+		router := setupTestRouterForMFA(mockMFAService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/me/mfa/totp", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response["otpauth_uri"] != "otpauth://totp/Movie%20Rental:test-user-id" {
+			t.Errorf("Unexpected otpauth_uri: %v", response["otpauth_uri"])
+		}
+	})
+
+	t.Run("already enabled", func(t *testing.T) {
+		mockMFAService := &MockMFAService{
+			EnrollTOTPFunc: func(userId string) (map[string]interface{}, error) {
+				return nil, services.ErrMFAAlreadyEnabled
+			},
+		}
+		router := setupTestRouterForMFA(mockMFAService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/me/mfa/totp", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusConflict, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestVerifyTOTPEnrollment(t *testing.T) {
+	t.Run("missing code", func(t *testing.T) {
+		router := setupTestRouterForMFA(&MockMFAService{})
+
+		req, _ := http.NewRequest(http.MethodPost, "/me/mfa/totp/verify", bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		mockMFAService := &MockMFAService{
+			VerifyTOTPEnrollmentFunc: func(userId string, sessionId string, code string) (map[string]interface{}, error) {
+				return nil, services.ErrInvalidMFACode
+			},
+		}
+		router := setupTestRouterForMFA(mockMFAService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/me/mfa/totp/verify", bytes.NewBufferString(`{"code":"000000"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestCompleteMFALogin(t *testing.T) {
+	t.Run("successful login", func(t *testing.T) {
+		mockMFAService := &MockMFAService{
+			CompleteLoginFunc: func(mfaToken string, code string, client services.ClientInfo) (map[string]interface{}, error) {
+				if mfaToken != "challenge" || code != "123456" {
+					t.Errorf("Unexpected arguments: %q, %q", mfaToken, code)
+				}
+				return map[string]interface{}{"message": "Login successful!", "token": "mock-jwt-token"}, nil
+			},
+		}
+		router := setupTestRouterForMFA(mockMFAService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/login/mfa", bytes.NewBufferString(`{"mfa_token":"challenge","code":"123456"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response["token"] != "mock-jwt-token" {
+			t.Errorf("Expected token 'mock-jwt-token', got %v", response["token"])
+		}
+	})
+
+	t.Run("invalid code", func(t *testing.T) {
+		mockMFAService := &MockMFAService{
+			CompleteLoginFunc: func(mfaToken string, code string, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, services.ErrInvalidMFACode
+			},
+		}
+		router := setupTestRouterForMFA(mockMFAService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/login/mfa", bytes.NewBufferString(`{"mfa_token":"challenge","code":"000000"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusUnauthorized, w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,32 @@
+package controller
+
+import (
+	"movierental/pkg/movie/movieExternalApi"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QuotaReporter reports how much of the movie provider budget is left.
+type QuotaReporter interface {
+	Status() movieExternalApi.QuotaStatus
+}
+
+type MovieAPIController struct {
+	Quota QuotaReporter
+}
+
+// QuotaStatus
+// @Summary Show the movie provider budget
+// @Description Admin only. Reports calls made to the paid movie provider today and this month against the configured budgets, the limit the provider last reported, and whether only cached responses are being served.
+// @Tags admin
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} movieExternalApi.QuotaStatus "Current budget use"
+// @Failure 401 {object} object{error=string} "Unauthorized: Missing or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: Admin role required"
+// @Router /admin/movie-api/quota [get]
+func (mc *MovieAPIController) QuotaStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, mc.Quota.Status())
+}
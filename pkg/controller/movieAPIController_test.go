@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"encoding/json"
+	"movierental/pkg/movie/movieExternalApi"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockQuotaReporter struct {
+	StatusFunc func() movieExternalApi.QuotaStatus
+}
+
+func (m *MockQuotaReporter) Status() movieExternalApi.QuotaStatus {
+	return m.StatusFunc()
+}
+
+func TestQuotaStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	remaining := 40
+	movieAPIController := &MovieAPIController{Quota: &MockQuotaReporter{
+		StatusFunc: func() movieExternalApi.QuotaStatus {
+			return movieExternalApi.QuotaStatus{CacheOnly: true, Daily: movieExternalApi.QuotaWindow{Budget: 1000, Used: 960, Remaining: &remaining}}
+		},
+	}}
+	router.GET("/admin/movie-api/quota", movieAPIController.QuotaStatus)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/movie-api/quota", nil)
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var response movieExternalApi.QuotaStatus
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !response.CacheOnly || response.Daily.Remaining == nil || *response.Daily.Remaining != 40 {
+		t.Errorf("Expected the quota status, got %s", w.Body.String())
+	}
+}
@@ -1,7 +1,10 @@
 package controller
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"movierental/pkg/movie/movieExternalApi"
 	"movierental/pkg/services"
 	"net/http"
 	"strconv"
@@ -18,6 +21,7 @@ type MovieController struct {
 // @Description Retrieves a list of movies from an external API, with optional filtering and pagination.
 // @Tags movies
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
 // @Param limit query int false "Number of movies to return per page (default: 20)" default(20)
 // @Param page query int false "Page number for pagination (default: 1)" default(1)
@@ -33,6 +37,8 @@ type MovieController struct {
 // @Failure 401 {object} object{error=string} "Unauthorized: Missing or invalid token"
 // @Failure 500 {object} object{error=string} "Internal Server Error: Failed to retrieve movies from external API"
 // @Failure 502 {object} object{error=string} "Bad Gateway: External API returned an error"
+// @Failure 503 {object} object{error=string} "Service Unavailable: Movie provider is failing or its budget is used up, and calls are suspended"
+// @Failure 504 {object} object{error=string} "Gateway Timeout: External API did not respond in time"
 // @Router /listallmovies [get]
 func (mc *MovieController) ListAllMovies(c *gin.Context) {
 	queryParams := make(map[string]string)
@@ -85,15 +91,21 @@ func (mc *MovieController) ListAllMovies(c *gin.Context) {
 		queryParams["with_rt_ratings"] = withRTRatings
 	}
 
-	movies, err := mc.MovieService.ListAllMovies(queryParams)
+	movies, err := mc.MovieService.ListAllMovies(c.Request.Context(), queryParams)
 	if err != nil {
-		log.Printf("Error listing all movies: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error listing all movies", "error", err)
 		if err.Error() == "external API returned non-OK status: ok, Message: No movies were found that matched the criteria." {
 			c.JSON(http.StatusNotFound, gin.H{"error": "No movies found matching the criteria."})
 		} else if err.Error() == "external API returned non-OK status: error, Message: Invalid or missing parameter: limit" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing parameter: limit"})
 		} else if err.Error() == "external API returned non-OK status: error, Message: Invalid or missing parameter: page" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or missing parameter: page"})
+		} else if errors.Is(err, movieExternalApi.ErrCircuitOpen) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie provider is temporarily unavailable."})
+		} else if errors.Is(err, movieExternalApi.ErrQuotaExhausted) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie provider budget is used up; only previously fetched results are available."})
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Timed out retrieving movies."})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve movies."})
 		}
@@ -107,6 +119,7 @@ func (mc *MovieController) ListAllMovies(c *gin.Context) {
 // @Description Retrieves detailed information for a specific movie by its ID. Requires authentication.
 // @Tags movies
 // @Security BearerAuth
+// @Security ApiKeyAuth
 // @Produce json
 // @Param movie_id query int true "ID of the movie to retrieve details for"
 // @Success 200 {object} movieExternalApi.Movie "Successfully retrieved movie details"
@@ -114,6 +127,8 @@ func (mc *MovieController) ListAllMovies(c *gin.Context) {
 // @Failure 401 {object} object{error=string} "Unauthorized: Missing or invalid token"
 // @Failure 500 {object} object{error=string} "Internal Server Error: Failed to retrieve movie details from external API"
 // @Failure 502 {object} object{error=string} "Bad Gateway: External API returned an error"
+// @Failure 503 {object} object{error=string} "Service Unavailable: Movie provider is failing or its budget is used up, and calls are suspended"
+// @Failure 504 {object} object{error=string} "Gateway Timeout: External API did not respond in time"
 // @Router /movie [get]
 func (mc *MovieController) MovieDetails(c *gin.Context) {
 	movieId := c.Query("movie_id")
@@ -122,11 +137,17 @@ func (mc *MovieController) MovieDetails(c *gin.Context) {
 		return
 	}
 
-	movie, err := mc.MovieService.GetMovieDetails(movieId)
+	movie, err := mc.MovieService.GetMovieDetails(c.Request.Context(), movieId)
 	if err != nil {
-		log.Printf("Error getting movie details: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Error getting movie details", "error", err)
 		if err.Error() == "external API returned non-OK status: ok, Message: Movie not found!" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Movie not found!"})
+		} else if errors.Is(err, movieExternalApi.ErrCircuitOpen) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie provider is temporarily unavailable."})
+		} else if errors.Is(err, movieExternalApi.ErrQuotaExhausted) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Movie provider budget is used up; only previously fetched results are available."})
+		} else if errors.Is(err, context.DeadlineExceeded) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Timed out retrieving movie details."})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve movie details."})
 		}
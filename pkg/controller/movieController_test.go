@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 
@@ -17,14 +19,14 @@ type MockMovieService struct {
 	GetMovieDetailsFunc func(movieId string) (movieExternalApi.Movie, error)
 }
 
-func (m *MockMovieService) ListAllMovies(queryParams map[string]string) ([]movieExternalApi.Movie, error) {
+func (m *MockMovieService) ListAllMovies(ctx context.Context, queryParams map[string]string) ([]movieExternalApi.Movie, error) {
 	if m.ListAllMoviesFunc != nil {
 		return m.ListAllMoviesFunc(queryParams)
 	}
 	return nil, errors.New("ListAllMoviesFunc not implemented")
 }
 
-func (m *MockMovieService) GetMovieDetails(movieId string) (movieExternalApi.Movie, error) {
+func (m *MockMovieService) GetMovieDetails(ctx context.Context, movieId string) (movieExternalApi.Movie, error) {
 	if m.GetMovieDetailsFunc != nil {
 		return m.GetMovieDetailsFunc(movieId)
 	}
@@ -247,4 +249,21 @@ func TestMovieDetails(t *testing.T) {
 			t.Errorf("Expected error 'Failed to retrieve movie details.', got %v", response["error"])
 		}
 	})
+
+	t.Run("service times out", func(t *testing.T) {
+		mockMovieService := &MockMovieService{
+			GetMovieDetailsFunc: func(movieId string) (movieExternalApi.Movie, error) {
+				return movieExternalApi.Movie{}, fmt.Errorf("error calling external RapidAPI: %w", context.DeadlineExceeded)
+			},
+		}
+		router := setupTestRouterForMovie(mockMovieService)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/movie?movie_id=123", nil)
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusGatewayTimeout, w.Code, w.Body.String())
+		}
+	})
 }
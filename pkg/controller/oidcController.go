@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"movierental/pkg/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/auth/oidc"
+	oidcStateCookieAge  = 600
+)
+
+type OIDCController struct {
+	OIDCService services.OIDCServiceInterface
+}
+
+// StartOIDCLogin
+// @Summary Start a social login
+// @Description Redirects to the external identity provider using the authorization code flow with PKCE.
+// @Tags users
+// @Param provider path string true "Configured identity provider name"
+// @Success 302 "Redirect to the identity provider"
+// @Failure 404 {object} object{error=string} "Not Found: Unknown identity provider"
+// @Failure 502 {object} object{error=string} "Bad Gateway: Identity provider unavailable"
+// @Router /auth/oidc/{provider}/login [get]
+func (oc *OIDCController) StartOIDCLogin(c *gin.Context) {
+	authURL, stateToken, err := oc.OIDCService.StartLogin(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error starting OIDC login", "error", err)
+		if errors.Is(err, services.ErrUnknownOIDCProvider) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider is unavailable."})
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, stateToken, oidcStateCookieAge, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback
+// @Summary Complete a social login
+// @Description Callback for the external identity provider. Links the external identity to a user, creating the user and cart on first login, and returns a JWT token or an MFA challenge.
+// @Tags users
+// @Produce json
+// @Param provider path string true "Configured identity provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State returned by the identity provider"
+// @Success 200 {object} object{message=string,token=string,refresh_token=string,session_id=string,user_id=string,created=bool} "Login successful"
+// @Failure 400 {object} object{error=string} "Bad Request: Missing parameters or invalid login state"
+// @Failure 404 {object} object{error=string} "Not Found: Unknown identity provider"
+// @Failure 409 {object} object{error=string} "Conflict: Email belongs to an existing account"
+// @Failure 502 {object} object{error=string} "Bad Gateway: Identity provider rejected the login"
+// @Router /auth/oidc/{provider}/callback [get]
+func (oc *OIDCController) OIDCCallback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Identity provider returned an error: " + providerErr})
+		return
+	}
+	code := c.Query("code")
+	state := c.Query("state")
+	if code == "" || state == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required query parameters: code and state"})
+		return
+	}
+	stateToken, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": services.ErrInvalidOIDCState.Error()})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", c.Request.TLS != nil, true)
+
+	response, err := oc.OIDCService.CompleteLogin(c.Request.Context(), c.Param("provider"), code, state, stateToken, clientInfo(c))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error completing OIDC login", "error", err)
+		switch {
+		case errors.Is(err, services.ErrUnknownOIDCProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrInvalidOIDCState):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrOIDCEmailConflict):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to complete login with the identity provider."})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"movierental/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockOIDCService struct {
+	StartLoginFunc    func(providerName string) (string, string, error)
+	CompleteLoginFunc func(providerName string, code string, state string, stateToken string, client services.ClientInfo) (map[string]interface{}, error)
+}
+
+func (m *MockOIDCService) StartLogin(ctx context.Context, providerName string) (string, string, error) {
+	if m.StartLoginFunc != nil {
+		return m.StartLoginFunc(providerName)
+	}
+	return "", "", errors.New("StartLoginFunc not implemented")
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, providerName string, code string, state string, stateToken string, client services.ClientInfo) (map[string]interface{}, error) {
+	if m.CompleteLoginFunc != nil {
+		return m.CompleteLoginFunc(providerName, code, state, stateToken, client)
+	}
+	return nil, errors.New("CompleteLoginFunc not implemented")
+}
+
+func setupTestRouterForOIDC(mockOIDCService *MockOIDCService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	oidcController := &OIDCController{OIDCService: mockOIDCService}
+	router.GET("/auth/oidc/:provider/login", oidcController.StartOIDCLogin)
+	router.GET("/auth/oidc/:provider/callback", oidcController.OIDCCallback)
+	return router
+}
+
+func TestStartOIDCLogin(t *testing.T) {
+	t.Run("redirects and sets state cookie", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{
+			StartLoginFunc: func(providerName string) (string, string, error) {
+				return "https://idp.example.com/authorize?state=abc", "signed-state", nil
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/google/login", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusFound, w.Code)
+		}
+		if location := w.Header().Get("Location"); location != "https://idp.example.com/authorize?state=abc" {
+			t.Errorf("Unexpected redirect location: %s", location)
+		}
+		cookie := w.Header().Get("Set-Cookie")
+		if !strings.Contains(cookie, "oidc_state=signed-state") || !strings.Contains(cookie, "HttpOnly") {
+			t.Errorf("Expected HttpOnly state cookie, got: %s", cookie)
+		}
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{
+			StartLoginFunc: func(providerName string) (string, string, error) {
+				return "", "", services.ErrUnknownOIDCProvider
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/unknown/login", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
+
+func TestOIDCCallback(t *testing.T) {
+	callback := func(router *gin.Engine, withCookie bool) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, "/auth/oidc/google/callback?code=abc&state=xyz", nil)
+		if withCookie {
+			req.AddCookie(&http.Cookie{Name: "oidc_state", Value: "signed-state"})
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("successful login", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{
+			CompleteLoginFunc: func(providerName string, code string, state string, stateToken string, client services.ClientInfo) (map[string]interface{}, error) {
+				if providerName != "google" || code != "abc" || state != "xyz" || stateToken != "signed-state" {
+					t.Errorf("Unexpected arguments: %s %s %s %s", providerName, code, state, stateToken)
+				}
+				return map[string]interface{}{"message": "Login successful", "token": "jwt"}, nil
+			},
+		})
+
+		w := callback(router, true)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("missing state cookie", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{})
+		w := callback(router, false)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("email conflict", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{
+			CompleteLoginFunc: func(providerName string, code string, state string, stateToken string, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, services.ErrOIDCEmailConflict
+			},
+		})
+		w := callback(router, true)
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d, got %d", http.StatusConflict, w.Code)
+		}
+	})
+
+	t.Run("provider failure", func(t *testing.T) {
+		router := setupTestRouterForOIDC(&MockOIDCService{
+			CompleteLoginFunc: func(providerName string, code string, state string, stateToken string, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, errors.New("token endpoint returned 400")
+			},
+		})
+		w := callback(router, true)
+		if w.Code != http.StatusBadGateway {
+			t.Errorf("Expected status %d, got %d", http.StatusBadGateway, w.Code)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"errors"
+	"log/slog"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/services"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionController struct {
+	SessionService services.SessionServiceInterface
+}
+
+// RefreshToken
+// @Summary Refresh an access token
+// @Description Exchanges a refresh token for a new access token. The refresh token is rotated; use the one in the response for the next refresh.
+// @Tags sessions
+// @Accept json
+// @Produce json
+// @Param refresh body requests.RefreshToken true "Refresh token from login"
+// @Success 200 {object} object{message=string,token=string,refresh_token=string} "Token refreshed"
+// @Failure 400 {object} object{error=string} "Bad Request: Invalid input data"
+// @Failure 401 {object} object{error=string} "Unauthorized: Invalid, expired or revoked refresh token"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /token/refresh [post]
+func (sc *SessionController) RefreshToken(c *gin.Context) {
+	var refreshReq requests.RefreshToken
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for refresh token request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := sc.SessionService.Refresh(c.Request.Context(), refreshReq.RefreshToken, clientInfo(c))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error refreshing token", "error", err)
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ListSessions
+// @Summary List active sessions
+// @Description Lists the devices the authenticated user is signed in on, marking the current one.
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} object{sessions=[]object{id=string,user_agent=string,ip_address=string,created_at=string,last_seen_at=string,expires_at=string,current=bool}} "Active sessions"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/sessions [get]
+func (sc *SessionController) ListSessions(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := sc.SessionService.ListSessions(c.Request.Context(), userId.(string), c.GetString("sessionId"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error listing sessions", "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession
+// @Summary Terminate a session
+// @Description Signs the authenticated user out of one session. Its refresh token and access tokens stop working immediately.
+// @Tags sessions
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} object{message=string,id=string} "Session terminated"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 404 {object} object{error=string} "Not Found: Session not found"
+// @Failure 500 {object} object{error=string} "Internal server error"
+// @Router /me/sessions/{id} [delete]
+func (sc *SessionController) RevokeSession(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := sc.SessionService.RevokeSession(c.Request.Context(), userId.(string), c.Param("id"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error revoking session", "error", err)
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"movierental/pkg/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MockSessionService struct {
+	RefreshFunc       func(refreshToken string, client services.ClientInfo) (map[string]interface{}, error)
+	ListSessionsFunc  func(userId string, currentSessionId string) (map[string]interface{}, error)
+	RevokeSessionFunc func(userId string, sessionId string) (map[string]interface{}, error)
+}
+
+func (m *MockSessionService) Refresh(ctx context.Context, refreshToken string, client services.ClientInfo) (map[string]interface{}, error) {
+	if m.RefreshFunc != nil {
+		return m.RefreshFunc(refreshToken, client)
+	}
+	return nil, errors.New("RefreshFunc not implemented")
+}
+
+func (m *MockSessionService) ListSessions(ctx context.Context, userId string, currentSessionId string) (map[string]interface{}, error) {
+	if m.ListSessionsFunc != nil {
+		return m.ListSessionsFunc(userId, currentSessionId)
+	}
+	return nil, errors.New("ListSessionsFunc not implemented")
+}
+
+func (m *MockSessionService) RevokeSession(ctx context.Context, userId string, sessionId string) (map[string]interface{}, error) {
+	if m.RevokeSessionFunc != nil {
+		return m.RevokeSessionFunc(userId, sessionId)
+	}
+	return nil, errors.New("RevokeSessionFunc not implemented")
+}
+
+func setupTestRouterForSessions(mockSessionService *MockSessionService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	sessionController := &SessionController{SessionService: mockSessionService}
+	router.POST("/token/refresh", sessionController.RefreshToken)
+
+	authenticated := router.Group("/")
+	authenticated.Use(func(c *gin.Context) {
+		c.Set("userId", "test-user-id")
+		c.Set("sessionId", "session-1")
+		c.Next()
+	})
+	authenticated.GET("/me/sessions", sessionController.ListSessions)
+	authenticated.DELETE("/me/sessions/:id", sessionController.RevokeSession)
+	return router
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Run("successful refresh", func(t *testing.T) {
+		router := setupTestRouterForSessions(&MockSessionService{
+			RefreshFunc: func(refreshToken string, client services.ClientInfo) (map[string]interface{}, error) {
+				if refreshToken != "refresh-1" || client.UserAgent != "test-agent" {
+					t.Errorf("Unexpected arguments: %q %+v", refreshToken, client)
+				}
+				return map[string]interface{}{"token": "access", "refresh_token": "refresh-2"}, nil
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBufferString(`{"refresh_token":"refresh-1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "test-agent")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("invalid refresh token", func(t *testing.T) {
+		router := setupTestRouterForSessions(&MockSessionService{
+			RefreshFunc: func(refreshToken string, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, services.ErrInvalidRefreshToken
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodPost, "/token/refresh", bytes.NewBufferString(`{"refresh_token":"stale"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+	})
+}
+
+func TestSessions(t *testing.T) {
+	t.Run("list passes the current session", func(t *testing.T) {
+		router := setupTestRouterForSessions(&MockSessionService{
+			ListSessionsFunc: func(userId string, currentSessionId string) (map[string]interface{}, error) {
+				if currentSessionId != "session-1" {
+					t.Errorf("Expected current session 'session-1', got %q", currentSessionId)
+				}
+				return map[string]interface{}{"sessions": []interface{}{}}, nil
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/me/sessions", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var response map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+	})
+
+	t.Run("revoke unknown session", func(t *testing.T) {
+		router := setupTestRouterForSessions(&MockSessionService{
+			RevokeSessionFunc: func(userId string, sessionId string) (map[string]interface{}, error) {
+				return nil, services.ErrSessionNotFound
+			},
+		})
+
+		req, _ := http.NewRequest(http.MethodDelete, "/me/sessions/missing", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	})
+}
@@ -1,11 +1,14 @@
 package controller
 
 import (
-	"log"
-	"movierental/pkg/models"
+	"errors"
+	"log/slog"
+	"math"
 	"movierental/pkg/models/requests"
 	"movierental/pkg/services"
+	"movierental/pkg/utils"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,22 +25,27 @@ type UserController struct {
 // @Produce json
 // @Param user body requests.CreateUser true "User registration details (username, email, password)"
 // @Success 200 {object} object{message=string,user_id=string,username=string,email=string,cart_id=string} "User and cart created successfully"
-// @Failure 400 {object} object{error=string} "Bad Request: Invalid input data"
+// @Failure 400 {object} object{error=string,violations=[]utils.PasswordRuleViolation} "Bad Request: Invalid input data or password policy violations"
 // @Failure 409 {object} object{error=string} "Conflict: Username or email already exists"
 // @Failure 500 {object} object{error=string} "Internal Server Error: Failed to create user or cart due to database/server error"
 // @Router /users [post]
 func (uc *UserController) CreateUser(c *gin.Context) {
 	var userReq requests.CreateUser
 	if err := c.ShouldBindJSON(&userReq); err != nil {
-		log.Printf("Validation error for user creation request: %v", err)
+		slog.WarnContext(c.Request.Context(), "Validation error for user creation request", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	response, err := uc.UserService.CreateUser(userReq)
+	response, err := uc.UserService.CreateUser(c.Request.Context(), userReq)
 	if err != nil {
-		log.Printf("Error creating user: %v", err)
-		if err.Error() == "username or email already exists. Please choose a different one" {
+		slog.ErrorContext(c.Request.Context(), "Error creating user", "error", err)
+		if writePasswordPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidUsername) || errors.Is(err, services.ErrInvalidEmail) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if err.Error() == "username or email already exists. Please choose a different one" {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -49,31 +57,195 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 
 // LoginUser
 // @Summary Authenticate user and get JWT token
-// @Description Authenticates a user with email and password, returning a JWT token upon successful login.
+// @Description Authenticates a user with a username or email (case-insensitive) and password, returning a JWT token upon successful login. Repeated failures for an account or client IP are throttled and eventually locked out.
 // @Tags users
 // @Accept json
 // @Produce json
-// @Param credentials body object{email=string,password=string} true "User login credentials (email and password)"
-// @Success 200 {object} object{message=string,token=string} "Login successful, returns JWT token"
+// @Param credentials body requests.Login true "User login credentials (identifier, email or username, and password)"
+// @Success 200 {object} object{message=string,token=string,refresh_token=string,session_id=string} "Login successful, returns JWT and refresh token"
 // @Failure 400 {object} object{error=string} "Bad Request: Invalid input data"
-// @Failure 401 {object} object{error=string} "Unauthorized: Incorrect password"
-// @Failure 404 {object} object{error=string} "Not Found: User not found with provided email"
+// @Failure 401 {object} object{error=string} "Unauthorized: Invalid credentials"
+// @Failure 429 {object} object{error=string} "Too Many Requests: Too many failed attempts, see Retry-After"
 // @Failure 500 {object} object{error=string} "Internal Server Error: Failed to retrieve user or generate token"
 // @Router /login [post]
 func (uc *UserController) LoginUser(c *gin.Context) {
-	var loginReq models.User
+	var loginReq requests.Login
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		log.Printf("Validation error for user login request: %v", err)
+		slog.WarnContext(c.Request.Context(), "Validation error for user login request", "error", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if loginReq.LoginIdentifier() == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An identifier, email or username is required."})
+		return
+	}
 
-	response, err := uc.UserService.LoginUser(loginReq)
+	response, err := uc.UserService.LoginUser(c.Request.Context(), loginReq, clientInfo(c))
 	if err != nil {
-		log.Printf("Error logging in user: %v", err)
-		if err.Error() == "incorrect password" {
+		slog.ErrorContext(c.Request.Context(), "Error logging in user", "error", err)
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrInvalidCredentials) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		} else if err.Error() == "user '' not found. Please ensure the user exists" {
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ChangePassword
+// @Summary Change the authenticated user's password
+// @Description Verifies the current password and replaces it with a new one that satisfies the password policy.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param passwords body requests.ChangePassword true "Current and new password"
+// @Success 200 {object} object{message=string} "Password changed"
+// @Failure 400 {object} object{error=string,violations=[]utils.PasswordRuleViolation} "Bad Request: Invalid input data or password policy violations"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: Current password is incorrect"
+// @Failure 500 {object} object{error=string} "Internal Server Error"
+// @Router /me/password [put]
+func (uc *UserController) ChangePassword(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+	var changeReq requests.ChangePassword
+	if err := c.ShouldBindJSON(&changeReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for change password request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := uc.UserService.ChangePassword(c.Request.Context(), userId.(string), changeReq)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error changing password", "error", err)
+		if writePasswordPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrIncorrectPassword) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreatePasswordReset
+// @Summary Issue a password reset token for a user
+// @Description Admin only. Creates a one-time, expiring reset token that the administrator passes to the user through a trusted channel.
+// @Tags admin
+// @Security BearerAuth
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path string true "ID of the user whose password should be reset"
+// @Success 200 {object} object{message=string,user_id=string,reset_token=string,expires_at=string} "Reset token created"
+// @Failure 401 {object} object{error=string} "Unauthorized: Missing or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: Admin role required"
+// @Failure 404 {object} object{error=string} "Not Found: User not found"
+// @Failure 500 {object} object{error=string} "Internal Server Error"
+// @Router /admin/users/{id}/password-reset [post]
+func (uc *UserController) CreatePasswordReset(c *gin.Context) {
+	adminId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+
+	response, err := uc.UserService.CreatePasswordReset(c.Request.Context(), adminId.(string), c.Param("id"))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error creating password reset", "error", err)
+		if errors.Is(err, services.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ResetPassword
+// @Summary Reset a password with a reset token
+// @Description Sets a new password using a one-time reset token issued by an administrator.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param reset body requests.ResetPassword true "Reset token and new password"
+// @Success 200 {object} object{message=string} "Password reset"
+// @Failure 400 {object} object{error=string,violations=[]utils.PasswordRuleViolation} "Bad Request: Invalid input, invalid token or password policy violations"
+// @Failure 500 {object} object{error=string} "Internal Server Error"
+// @Router /password/reset [post]
+func (uc *UserController) ResetPassword(c *gin.Context) {
+	var resetReq requests.ResetPassword
+	if err := c.ShouldBindJSON(&resetReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for reset password request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := uc.UserService.ResetPassword(c.Request.Context(), resetReq)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error resetting password", "error", err)
+		if writePasswordPolicyError(c, err) {
+			return
+		}
+		if errors.Is(err, services.ErrInvalidResetToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateScopedToken
+// @Summary Create a scoped access token
+// @Description Issues a short-lived access token restricted to a subset of the caller's scopes, e.g. a read-only token for a dashboard.
+// @Tags users
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param token body requests.CreateScopedToken true "Scopes and optional lifetime in minutes"
+// @Success 200 {object} object{message=string,token=string,scopes=[]string,expires_at=string} "Token created"
+// @Failure 400 {object} object{error=string} "Bad Request: Invalid input or scopes exceeding the caller's"
+// @Failure 401 {object} object{error=string} "Unauthorized: unable to get userId from context or invalid token"
+// @Failure 403 {object} object{error=string} "Forbidden: Insufficient scope"
+// @Failure 500 {object} object{error=string} "Internal Server Error"
+// @Router /me/tokens [post]
+func (uc *UserController) CreateScopedToken(c *gin.Context) {
+	userId, exists := c.Get("userId")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unable to get userId from context"})
+		return
+	}
+	scopes, _ := c.Get("scopes")
+	grantedScopes, _ := scopes.([]string)
+	var tokenReq requests.CreateScopedToken
+	if err := c.ShouldBindJSON(&tokenReq); err != nil {
+		slog.WarnContext(c.Request.Context(), "Validation error for scoped token request", "error", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := uc.UserService.CreateScopedToken(c.Request.Context(), userId.(string), c.GetString("sessionId"), grantedScopes, tokenReq)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error creating scoped token", "error", err)
+		if errors.Is(err, services.ErrInvalidScope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		} else if errors.Is(err, services.ErrUserNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -82,3 +254,15 @@ func (uc *UserController) LoginUser(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, response)
 }
+
+func writePasswordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *utils.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":      "password does not meet the password policy",
+		"violations": policyErr.Violations,
+	})
+	return true
+}
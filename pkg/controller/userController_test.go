@@ -2,43 +2,94 @@ package controller
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"movierental/config"
 	"movierental/pkg/models"
 	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/server"
+	"movierental/pkg/services"
+	"movierental/pkg/utils"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type MockUserService struct {
-	CreateUserFunc func(userReq requests.CreateUser) (map[string]interface{}, error)
-	LoginUserFunc  func(loginReq models.User) (map[string]interface{}, error)
+	CreateUserFunc          func(userReq requests.CreateUser) (map[string]interface{}, error)
+	LoginUserFunc           func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error)
+	ChangePasswordFunc      func(userId string, changeReq requests.ChangePassword) (map[string]interface{}, error)
+	CreatePasswordResetFunc func(adminId string, userId string) (map[string]interface{}, error)
+	ResetPasswordFunc       func(resetReq requests.ResetPassword) (map[string]interface{}, error)
+	CreateScopedTokenFunc   func(userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error)
 }
 
-func (m *MockUserService) CreateUser(userReq requests.CreateUser) (map[string]interface{}, error) {
+func (m *MockUserService) CreateUser(ctx context.Context, userReq requests.CreateUser) (map[string]interface{}, error) {
 	if m.CreateUserFunc != nil {
 		return m.CreateUserFunc(userReq)
 	}
 	return nil, errors.New("CreateUserFunc not implemented")
 }
 
-func (m *MockUserService) LoginUser(loginReq models.User) (map[string]interface{}, error) {
+func (m *MockUserService) LoginUser(ctx context.Context, loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
 	if m.LoginUserFunc != nil {
-		return m.LoginUserFunc(loginReq)
+		return m.LoginUserFunc(loginReq, client)
 	}
 	return nil, errors.New("LoginUserFunc not implemented")
 }
 
+func (m *MockUserService) ChangePassword(ctx context.Context, userId string, changeReq requests.ChangePassword) (map[string]interface{}, error) {
+	if m.ChangePasswordFunc != nil {
+		return m.ChangePasswordFunc(userId, changeReq)
+	}
+	return nil, errors.New("ChangePasswordFunc not implemented")
+}
+
+func (m *MockUserService) CreatePasswordReset(ctx context.Context, adminId string, userId string) (map[string]interface{}, error) {
+	if m.CreatePasswordResetFunc != nil {
+		return m.CreatePasswordResetFunc(adminId, userId)
+	}
+	return nil, errors.New("CreatePasswordResetFunc not implemented")
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, resetReq requests.ResetPassword) (map[string]interface{}, error) {
+	if m.ResetPasswordFunc != nil {
+		return m.ResetPasswordFunc(resetReq)
+	}
+	return nil, errors.New("ResetPasswordFunc not implemented")
+}
+
+func (m *MockUserService) CreateScopedToken(ctx context.Context, userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error) {
+	if m.CreateScopedTokenFunc != nil {
+		return m.CreateScopedTokenFunc(userId, sessionId, grantedScopes, tokenReq)
+	}
+	return nil, errors.New("CreateScopedTokenFunc not implemented")
+}
+
 func setupTestRouterForUser(mockUserService *MockUserService) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
 	userController := &UserController{UserService: mockUserService}
 	router.POST("/users", userController.CreateUser)
 	router.POST("/login", userController.LoginUser)
+	router.POST("/password/reset", userController.ResetPassword)
+
+	authenticated := router.Group("/")
+	authenticated.Use(func(c *gin.Context) {
+		c.Set("userId", "test-user-id")
+		c.Set("scopes", []string{"movies:read", "cart:read"})
+		c.Next()
+	})
+	authenticated.PUT("/me/password", userController.ChangePassword)
+	authenticated.POST("/me/tokens", userController.CreateScopedToken)
+	authenticated.POST("/admin/users/:id/password-reset", userController.CreatePasswordReset)
 	return router
 }
 
@@ -134,6 +185,39 @@ func TestCreateUser(t *testing.T) {
 		}
 	})
 
+	t.Run("service returns password policy violations", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			CreateUserFunc: func(userReq requests.CreateUser) (map[string]interface{}, error) {
+				return nil, &utils.PasswordPolicyError{Violations: []utils.PasswordRuleViolation{
+					{Rule: "min_length", Message: "must be at least 10 characters long"},
+					{Rule: "require_digit", Message: "must contain a digit"},
+				}}
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		userJSON := `{"username":"weakuser","email":"weak@example.com","password":"a"}`
+		req, _ := http.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(userJSON))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		var response struct {
+			Error      string                        `json:"error"`
+			Violations []utils.PasswordRuleViolation `json:"violations"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if len(response.Violations) != 2 || response.Violations[1].Rule != "require_digit" {
+			t.Errorf("Expected both violations in the response, got %+v", response.Violations)
+		}
+	})
+
 	t.Run("service returns internal server error", func(t *testing.T) {
 		mockUserService := &MockUserService{
 			CreateUserFunc: func(userReq requests.CreateUser) (map[string]interface{}, error) {
@@ -166,7 +250,7 @@ func TestCreateUser(t *testing.T) {
 func TestLoginUser(t *testing.T) {
 	t.Run("successful login", func(t *testing.T) {
 		mockUserService := &MockUserService{
-			LoginUserFunc: func(loginReq models.User) (map[string]interface{}, error) {
+			LoginUserFunc: func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
 				return map[string]interface{}{
 					"message": "Login successful!",
 					"token":   "mock-jwt-token",
@@ -224,10 +308,49 @@ func TestLoginUser(t *testing.T) {
 		}
 	})
 
-	t.Run("service returns incorrect password error", func(t *testing.T) {
+	t.Run("login by username", func(t *testing.T) {
 		mockUserService := &MockUserService{
-			LoginUserFunc: func(loginReq models.User) (map[string]interface{}, error) {
-				return nil, errors.New("incorrect password")
+			LoginUserFunc: func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
+				if loginReq.LoginIdentifier() != "MovieFan" {
+					t.Errorf("Expected identifier 'MovieFan', got %q", loginReq.LoginIdentifier())
+				}
+				return map[string]interface{}{"message": "Login successful!", "token": "mock-jwt-token"}, nil
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		loginJSON := `{"username":"MovieFan","password":"correctpassword"}`
+		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginJSON))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("missing identifier", func(t *testing.T) {
+		mockUserService := &MockUserService{}
+		router := setupTestRouterForUser(mockUserService)
+
+		loginJSON := `{"password":"correctpassword"}`
+		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginJSON))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("service returns invalid credentials error", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			LoginUserFunc: func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, services.ErrInvalidCredentials
 			},
 		}
 		router := setupTestRouterForUser(mockUserService)
@@ -247,42 +370,37 @@ func TestLoginUser(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to unmarshal response: %v", err)
 		}
-		if response["error"] != "incorrect password" {
-			t.Errorf("Expected error message 'incorrect password', got %v", response["error"])
+		if response["error"] != "invalid credentials" {
+			t.Errorf("Expected error message 'invalid credentials', got %v", response["error"])
 		}
 	})
 
-	t.Run("service returns user not found error", func(t *testing.T) {
+	t.Run("service returns throttled error", func(t *testing.T) {
 		mockUserService := &MockUserService{
-			LoginUserFunc: func(loginReq models.User) (map[string]interface{}, error) {
-				return nil, errors.New("user '' not found. Please ensure the user exists")
+			LoginUserFunc: func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
+				return nil, &services.LoginThrottledError{RetryAfter: 1500 * time.Millisecond}
 			},
 		}
 		router := setupTestRouterForUser(mockUserService)
 
-		loginJSON := `{"email":"nonexistent@example.com","password":"password123"}`
+		loginJSON := `{"email":"user@example.com","password":"password123"}`
 		req, _ := http.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginJSON))
 		req.Header.Set("Content-Type", "application/json")
 		w := httptest.NewRecorder()
 
 		router.ServeHTTP(w, req)
 
-		if w.Code != http.StatusNotFound {
-			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusNotFound, w.Code, w.Body.String())
+		if w.Code != http.StatusTooManyRequests {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusTooManyRequests, w.Code, w.Body.String())
 		}
-		var response map[string]string
-		err := json.Unmarshal(w.Body.Bytes(), &response)
-		if err != nil {
-			t.Fatalf("Failed to unmarshal response: %v", err)
-		}
-		if response["error"] != "user '' not found. Please ensure the user exists" {
-			t.Errorf("Expected error message 'user '' not found. Please ensure the user exists', got %v", response["error"])
+		if retryAfter := w.Header().Get("Retry-After"); retryAfter != "2" {
+			t.Errorf("Expected Retry-After '2', got %q", retryAfter)
 		}
 	})
 
 	t.Run("service returns internal server error", func(t *testing.T) {
 		mockUserService := &MockUserService{
-			LoginUserFunc: func(loginReq models.User) (map[string]interface{}, error) {
+			LoginUserFunc: func(loginReq requests.Login, client services.ClientInfo) (map[string]interface{}, error) {
 				return nil, errors.New("failed to generate token for login.")
 			},
 		}
@@ -308,3 +426,187 @@ func TestLoginUser(t *testing.T) {
 		}
 	})
 }
+
+func TestChangePassword(t *testing.T) {
+	t.Run("successful change", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			ChangePasswordFunc: func(userId string, changeReq requests.ChangePassword) (map[string]interface{}, error) {
+				if userId != "test-user-id" {
+					t.Errorf("Expected userId 'test-user-id', got %q", userId)
+				}
+				return map[string]interface{}{"message": "Password changed successfully!"}, nil
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		body := `{"current_password":"OldPassword1","new_password":"NewPassword12"}`
+		req, _ := http.NewRequest(http.MethodPut, "/me/password", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("incorrect current password", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			ChangePasswordFunc: func(userId string, changeReq requests.ChangePassword) (map[string]interface{}, error) {
+				return nil, services.ErrIncorrectPassword
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		body := `{"current_password":"wrong","new_password":"NewPassword12"}`
+		req, _ := http.NewRequest(http.MethodPut, "/me/password", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusForbidden, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Run("admin issues reset token", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			CreatePasswordResetFunc: func(adminId string, userId string) (map[string]interface{}, error) {
+				if userId != "target-user" {
+					t.Errorf("Expected userId 'target-user', got %q", userId)
+				}
+				return map[string]interface{}{"reset_token": "mock-reset-token"}, nil
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		req, _ := http.NewRequest(http.MethodPost, "/admin/users/target-user/password-reset", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("invalid reset token", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			ResetPasswordFunc: func(resetReq requests.ResetPassword) (map[string]interface{}, error) {
+				return nil, services.ErrInvalidResetToken
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		body := `{"token":"expired","new_password":"NewPassword12"}`
+		req, _ := http.NewRequest(http.MethodPost, "/password/reset", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+		var response map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response: %v", err)
+		}
+		if response["error"] != services.ErrInvalidResetToken.Error() {
+			t.Errorf("Expected error %q, got %v", services.ErrInvalidResetToken.Error(), response["error"])
+		}
+	})
+}
+
+func TestCreateScopedToken(t *testing.T) {
+	t.Run("passes the caller's scopes to the service", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			CreateScopedTokenFunc: func(userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error) {
+				if len(grantedScopes) != 2 || len(tokenReq.Scopes) != 1 || tokenReq.Scopes[0] != "movies:read" {
+					t.Errorf("Unexpected scopes: granted %v, requested %v", grantedScopes, tokenReq.Scopes)
+				}
+				return map[string]interface{}{"token": "scoped-token"}, nil
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		body := `{"scopes":["movies:read"],"expires_in_minutes":30}`
+		req, _ := http.NewRequest(http.MethodPost, "/me/tokens", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("scope exceeding the caller's is rejected", func(t *testing.T) {
+		mockUserService := &MockUserService{
+			CreateScopedTokenFunc: func(userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error) {
+				return nil, fmt.Errorf("%w: scope \"cart:write\" exceeds your permissions", services.ErrInvalidScope)
+			},
+		}
+		router := setupTestRouterForUser(mockUserService)
+
+		body := `{"scopes":["cart:write"]}`
+		req, _ := http.NewRequest(http.MethodPost, "/me/tokens", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d. Body: %s", http.StatusBadRequest, w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestLoginUser_IgnoresSpoofedForwardedFor(t *testing.T) {
+	config.AppConfig = config.Defaults()
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	gin.SetMode(gin.TestMode)
+	router, err := server.NewRouter(config.ServerConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	userController := &UserController{UserService: &services.UserService{
+		Store:      repository.NewStore(testDB),
+		LoginGuard: services.NewLoginGuard(config.LoginProtectionConfig{FreeAttempts: 1, MaxAccountFailures: 100, MaxIPFailures: 2}),
+	}}
+	router.POST("/login", userController.LoginUser)
+
+	// Each attempt names another account and claims another IP, so only the
+	// connection's own IP ties them together.
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		loginJSON := fmt.Sprintf(`{"email":"user%d@example.com","password":"wrong"}`, i)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(loginJSON))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("198.51.100.%d", i+1))
+		req.RemoteAddr = "203.0.113.7:4000"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != want {
+			t.Errorf("Attempt %d: expected status %d, got %d. Body: %s", i+1, want, w.Code, w.Body.String())
+		}
+	}
+
+	var auditLogs []models.AuditLog
+	testDB.Find(&auditLogs)
+	if len(auditLogs) == 0 {
+		t.Fatal("Expected the failed logins to be audited")
+	}
+	for _, entry := range auditLogs {
+		if entry.IPAddress != "203.0.113.7" {
+			t.Errorf("Expected the audit log to record the connection's IP, got %q for %s", entry.IPAddress, entry.Event)
+		}
+	}
+}
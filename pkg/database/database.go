@@ -1,29 +1,192 @@
 package database
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"movierental/config"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-var DB *gorm.DB
-
-func ConnectToDb() {
-	// dsn := "host=localhost user=nikhil.verma dbname=movie-rental-db port=5432 sslmode=disable"
-	dsn := fmt.Sprintf("host=%s user=%s dbname=%s port=%d sslmode=%s",
-		config.AppConfig.Database.Host,
-		config.AppConfig.Database.User,
-		config.AppConfig.Database.DBName,
-		config.AppConfig.Database.Port,
-		config.AppConfig.Database.SSLMode,
-	)
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// maxConnectBackoff caps the wait between connection attempts at startup.
+const maxConnectBackoff = 30 * time.Second
+
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Connect opens the database described by cfg and configures its connection
+// pool. While the database is unreachable it retries cfg.ConnectRetries
+// times with exponential backoff, giving up early if ctx is cancelled. The
+// caller owns the returned connection and passes it on to whatever needs it.
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	backoff := time.Duration(cfg.ConnectRetryBackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		db, err := Open(cfg)
+		if err == nil {
+			if err := configurePool(db, cfg); err != nil {
+				return nil, err
+			}
+			slog.InfoContext(ctx, "Connected to database", "target", describe(cfg))
+			return db, nil
+		}
+		if attempt > cfg.ConnectRetries {
+			return nil, fmt.Errorf("failed to connect to database %s after %d attempts: %w", describe(cfg), attempt, err)
+		}
+
+		slog.WarnContext(ctx, "Database not reachable, retrying",
+			"target", describe(cfg), "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up connecting to database %s: %w", describe(cfg), ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
+// Open opens the database described by cfg with the driver it names.
+// Driver errors such as unique constraint violations are translated to
+// GORM's own, e.g. gorm.ErrDuplicatedKey, so callers need not know which
+// database they talk to.
+func Open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	gormConfig := &gorm.Config{Logger: newLogger(), TranslateError: true}
+	switch cfg.Driver {
+	case DriverPostgres, "":
+		return gorm.Open(postgres.Open(DSN(cfg)), gormConfig)
+	case DriverSQLite:
+		return gorm.Open(sqlite.Open(SQLiteDSN(cfg.Path)), gormConfig)
+	}
+	return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
+}
+
+// SQLiteDSN returns the connection string for the SQLite file at path. It
+// waits for locks instead of failing at once, enforces foreign keys and uses
+// write-ahead logging so reads do not block on writes. A path that already
+// carries parameters is used as given.
+func SQLiteDSN(path string) string {
+	if strings.Contains(path, "?") {
+		return path
+	}
+	if path == ":memory:" {
+		return "file::memory:?cache=shared&_foreign_keys=on"
+	}
+	return "file:" + path + "?_busy_timeout=5000&_foreign_keys=on&_journal_mode=WAL"
+}
+
+// DSN returns the PostgreSQL connection string for cfg: its URL when set,
+// otherwise a key=value DSN built from the individual fields. A statement
+// timeout is added unless the URL already sets one.
+func DSN(cfg config.DatabaseConfig) string {
+	timeoutMillis := ""
+	if cfg.StatementTimeoutSeconds > 0 {
+		timeoutMillis = strconv.Itoa(cfg.StatementTimeoutSeconds * 1000)
+	}
+
+	if cfg.URL != "" {
+		if timeoutMillis == "" || strings.Contains(cfg.URL, "statement_timeout") {
+			return cfg.URL
+		}
+		if strings.HasPrefix(cfg.URL, "postgres://") || strings.HasPrefix(cfg.URL, "postgresql://") {
+			if parsed, err := url.Parse(cfg.URL); err == nil {
+				query := parsed.Query()
+				query.Set("statement_timeout", timeoutMillis)
+				parsed.RawQuery = query.Encode()
+				return parsed.String()
+			}
+			return cfg.URL
+		}
+		return cfg.URL + " statement_timeout=" + timeoutMillis
+	}
+
+	parts := []string{
+		"host=" + dsnValue(cfg.Host),
+		"port=" + strconv.Itoa(cfg.Port),
+		"user=" + dsnValue(cfg.User),
+		"dbname=" + dsnValue(cfg.DBName),
+		"sslmode=" + dsnValue(cfg.SSLMode),
+	}
+	if cfg.Password != "" {
+		parts = append(parts, "password="+dsnValue(cfg.Password))
+	}
+	if timeoutMillis != "" {
+		parts = append(parts, "statement_timeout="+timeoutMillis)
+	}
+	return strings.Join(parts, " ")
+}
+
+// dsnValue quotes value for a key=value DSN when it is empty or contains
+// spaces, quotes or backslashes.
+func dsnValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + escaped + "'"
+}
+
+// describe names the database for logs and errors without its credentials.
+func describe(cfg config.DatabaseConfig) string {
+	if cfg.Driver == DriverSQLite {
+		return "sqlite:" + cfg.Path
+	}
+	if cfg.URL != "" {
+		if parsed, err := url.Parse(cfg.URL); err == nil && parsed.Host != "" {
+			return parsed.Host + parsed.Path
+		}
+		return "from database.url"
+	}
+	return fmt.Sprintf("%s:%d/%s", cfg.Host, cfg.Port, cfg.DBName)
+}
+
+func configurePool(db *gorm.DB, cfg config.DatabaseConfig) error {
+	sqlDB, err := db.DB()
 	if err != nil {
-		panic("failed to connect database")
-	} else {
-		fmt.Println("Connected to database")
+		return fmt.Errorf("failed to access connection pool: %w", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetimeSeconds > 0 {
+		sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeSeconds) * time.Second)
 	}
+	if cfg.ConnMaxIdleTimeSeconds > 0 {
+		sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeSeconds) * time.Second)
+	}
+	if cfg.Driver == DriverSQLite && cfg.Path == ":memory:" {
+		// The in-memory database lives only as long as a connection to it,
+		// so keep exactly one open.
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+		sqlDB.SetConnMaxIdleTime(0)
+	}
+	return nil
+}
+
+// newLogger sends GORM's warnings and slow query reports to the default slog
+// logger. Queries are logged with placeholders, never with their arguments.
+func newLogger() logger.Interface {
+	return logger.New(slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn), logger.Config{
+		SlowThreshold:             200 * time.Millisecond,
+		LogLevel:                  logger.Warn,
+		IgnoreRecordNotFoundError: true,
+		ParameterizedQueries:      true,
+	})
 }
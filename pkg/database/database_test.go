@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/database/migrations"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestDSN(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.DatabaseConfig
+		want string
+	}{
+		{
+			name: "fields with password and statement timeout",
+			cfg:  config.DatabaseConfig{Host: "db", Port: 5432, User: "app", DBName: "movies", SSLMode: "disable", Password: "it's secret", StatementTimeoutSeconds: 30},
+			want: `host=db port=5432 user=app dbname=movies sslmode=disable password='it\'s secret' statement_timeout=30000`,
+		},
+		{
+			name: "fields without password",
+			cfg:  config.DatabaseConfig{Host: "db", Port: 5432, User: "app", DBName: "movies", SSLMode: "require"},
+			want: "host=db port=5432 user=app dbname=movies sslmode=require",
+		},
+		{
+			name: "URL overrides fields",
+			cfg:  config.DatabaseConfig{URL: "postgres://app:pw@db:5432/movies?sslmode=require", Host: "ignored", StatementTimeoutSeconds: 5},
+			want: "postgres://app:pw@db:5432/movies?sslmode=require&statement_timeout=5000",
+		},
+		{
+			name: "URL keeps its own statement timeout",
+			cfg:  config.DatabaseConfig{URL: "host=db statement_timeout=100", StatementTimeoutSeconds: 5},
+			want: "host=db statement_timeout=100",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DSN(tc.cfg); got != tc.want {
+				t.Errorf("DSN() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConnect_StopsRetryingWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// Nothing listens on port 1, so every attempt fails immediately.
+	cfg := config.DatabaseConfig{Host: "127.0.0.1", Port: 1, User: "app", DBName: "movies", SSLMode: "disable",
+		ConnectRetries: 10, ConnectRetryBackoffSeconds: 5}
+	start := time.Now()
+	_, err := Connect(ctx, cfg)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the context error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected Connect to return once cancelled, took %s", elapsed)
+	}
+}
+
+func TestConnect_SQLite(t *testing.T) {
+	cfg := config.DatabaseConfig{Driver: DriverSQLite, Path: filepath.Join(t.TempDir(), "movierental.db")}
+	db, err := Connect(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := migrations.Up(context.Background(), db); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	columns, err := db.Migrator().ColumnTypes(&requests.Cart{})
+	if err != nil {
+		t.Fatalf("Failed to read cart columns: %v", err)
+	}
+	for _, column := range columns {
+		if column.Name() == "movies" && !strings.EqualFold(column.DatabaseTypeName(), "text") {
+			t.Errorf("Expected the movies column to be text on SQLite, got %s", column.DatabaseTypeName())
+		}
+	}
+
+	user := models.User{ID: "user-1", Username: "alice", Email: "alice@example.com"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := db.Create(&user).Error; !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("Expected gorm.ErrDuplicatedKey for a duplicate, got: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// The baseline schema, as AutoMigrate created it before migrations were
+// versioned. The structs are frozen copies of the models at that point so
+// later model changes do not rewrite history; AutoMigrate only adds what is
+// missing, which lets databases created the old way adopt this migration.
+
+type initialUser struct {
+	ID              string `gorm:"primaryKey"`
+	Username        string `gorm:"unique;not null"`
+	Email           string `gorm:"unique;not null"`
+	Password        string `gorm:"not null"`
+	Role            string `gorm:"not null;default:user"`
+	TOTPSecret      string
+	TOTPEnabled     bool  `gorm:"not null;default:false"`
+	TOTPLastCounter int64 `gorm:"not null;default:0"`
+}
+
+func (initialUser) TableName() string { return "users" }
+
+type initialCartMovies []struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+}
+
+func (initialCartMovies) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "text"
+	}
+	return "json"
+}
+
+type initialCart struct {
+	Id     string `gorm:"primaryKey"`
+	UserId string
+	Movies initialCartMovies `gorm:"serializer:json"`
+}
+
+func (initialCart) TableName() string { return "carts" }
+
+type initialAuditLog struct {
+	ID         string `gorm:"primaryKey"`
+	Event      string `gorm:"not null;index"`
+	UserID     string `gorm:"index"`
+	Identifier string
+	IPAddress  string
+	Detail     string
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (initialAuditLog) TableName() string { return "audit_logs" }
+
+type initialMFARecoveryCode struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (initialMFARecoveryCode) TableName() string { return "mfa_recovery_codes" }
+
+type initialPasswordResetToken struct {
+	ID        string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	CreatedBy string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (initialPasswordResetToken) TableName() string { return "password_reset_tokens" }
+
+type initialUserIdentity struct {
+	ID          string `gorm:"primaryKey"`
+	UserID      string `gorm:"not null;index"`
+	Provider    string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+func (initialUserIdentity) TableName() string { return "user_identities" }
+
+type initialAPIKey struct {
+	ID         string `gorm:"primaryKey"`
+	UserID     string `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	Prefix     string `gorm:"not null;uniqueIndex"`
+	KeyHash    string `gorm:"not null;uniqueIndex"`
+	Scopes     string `gorm:"not null;default:''"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (initialAPIKey) TableName() string { return "api_keys" }
+
+type initialSession struct {
+	ID               string `gorm:"primaryKey"`
+	UserID           string `gorm:"not null;index"`
+	RefreshTokenHash string `gorm:"not null;uniqueIndex"`
+	UserAgent        string
+	IPAddress        string
+	CreatedAt        time.Time
+	LastSeenAt       time.Time
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+}
+
+func (initialSession) TableName() string { return "sessions" }
+
+func initialModels() []interface{} {
+	return []interface{}{
+		&initialUser{},
+		&initialCart{},
+		&initialAuditLog{},
+		&initialMFARecoveryCode{},
+		&initialPasswordResetToken{},
+		&initialUserIdentity{},
+		&initialAPIKey{},
+		&initialSession{},
+	}
+}
+
+func init() {
+	register(Migration{
+		Version: 20261018000001,
+		Name:    "create_initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(initialModels()...)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(initialModels()...)
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Normalizes existing emails and usernames to lower case and adds unique
+// indexes on their lower-cased values, so the database rejects accounts
+// differing only in case even if a writer skips normalization. Accounts that
+// would collide once normalized are listed instead, for the operator to
+// merge or rename before running it again. Down drops the indexes; the
+// normalized values stay as they are.
+func init() {
+	register(Migration{
+		Version: 20261018000002,
+		Name:    "user_identity_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"email", "username"} {
+				if err := checkNormalizedDuplicates(tx, column); err != nil {
+					return err
+				}
+			}
+			return execAll(tx,
+				"UPDATE users SET email = LOWER(TRIM(email)), username = LOWER(TRIM(username)) WHERE email <> LOWER(TRIM(email)) OR username <> LOWER(TRIM(username))",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))",
+				"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username))",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS idx_users_email_lower",
+				"DROP INDEX IF EXISTS idx_users_username_lower",
+			)
+		},
+	})
+}
+
+// checkNormalizedDuplicates returns an error naming the users whose column
+// values are equal once trimmed and lower-cased.
+func checkNormalizedDuplicates(tx *gorm.DB, column string) error {
+	normalized := "LOWER(TRIM(" + column + "))"
+	var rows []struct {
+		ID    string
+		Value string
+	}
+	err := tx.Table("users").
+		Select("id, "+column+" AS value").
+		Where(normalized+" IN (?)", tx.Table("users").Select(normalized).Group(normalized).Having("COUNT(*) > 1")).
+		Order(normalized + ", id").
+		Scan(&rows).Error
+	if err != nil {
+		return fmt.Errorf("failed to look for duplicate %ss: %w", column, err)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	conflicts := make([]string, 0, len(rows))
+	for _, row := range rows {
+		conflicts = append(conflicts, fmt.Sprintf("%s (%q)", row.ID, row.Value))
+	}
+	return fmt.Errorf("users with the same %s apart from case or surrounding spaces must be merged or renamed first: %s",
+		column, strings.Join(conflicts, ", "))
+}
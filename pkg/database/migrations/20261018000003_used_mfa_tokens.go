@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type usedMFAToken struct {
+	ID        string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    time.Time `gorm:"not null"`
+}
+
+func (usedMFAToken) TableName() string { return "used_mfa_tokens" }
+
+// Records MFA tokens that completed a login, so each can be redeemed once.
+func init() {
+	register(Migration{
+		Version: 20261018000003,
+		Name:    "used_mfa_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&usedMFAToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&usedMFAToken{})
+		},
+	})
+}
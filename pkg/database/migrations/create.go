@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`))
+
+// Create writes an empty migration named name into dir, versioned by now,
+// and returns its path.
+func Create(dir string, name string, now time.Time) (string, error) {
+	name = sanitizeName(name)
+	if name == "" {
+		return "", fmt.Errorf("migration name must contain letters or digits")
+	}
+	version := now.UTC().Format("20060102150405")
+	path := filepath.Join(dir, version+"_"+name+".go")
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create migration file: %w", err)
+	}
+	defer file.Close()
+	if err := migrationTemplate.Execute(file, struct {
+		Version string
+		Name    string
+	}{version, name}); err != nil {
+		return "", fmt.Errorf("failed to write migration file: %w", err)
+	}
+	return path, nil
+}
+
+// sanitizeName lower-cases name and joins its words with underscores.
+func sanitizeName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+	return strings.Join(words, "_")
+}
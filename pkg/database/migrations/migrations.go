@@ -0,0 +1,219 @@
+// Package migrations holds the ordered schema migrations and applies them,
+// recording each applied version in the schema_migrations table.
+//
+// A migration is a Go file registering itself from init. Migrations are
+// written against GORM's migrator and raw SQL portable to both PostgreSQL and
+// SQLite, and must never change once released: later schema changes go into
+// new migrations.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change. Versions are timestamps of the
+// form YYYYMMDDHHMMSS and define the order migrations run in. Up and Down
+// run inside a transaction together with the bookkeeping in
+// schema_migrations.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// Status describes a migration and when it was applied, if it was.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+	// Unknown is set for versions recorded in the database that this build
+	// has no migration for.
+	Unknown bool
+}
+
+var ErrUnknownMigration = errors.New("applied migration is not part of this build")
+
+var registry []Migration
+
+// register adds m to the migrations of this build. It is called from the
+// init function of each migration file.
+func register(m Migration) {
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migrations: duplicate version %d (%s and %s)", m.Version, existing.Name, m.Name))
+		}
+	}
+	registry = append(registry, m)
+}
+
+// All returns the migrations of this build in the order they apply.
+func All() []Migration {
+	all := append([]Migration(nil), registry...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// schemaMigration is a row of schema_migrations.
+type schemaMigration struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func Up(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	return up(ctx, db, All())
+}
+
+// Down rolls back the last steps applied migrations, newest first, and
+// returns the ones rolled back.
+func Down(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	return down(ctx, db, All(), steps)
+}
+
+// Pending returns the migrations not applied to db yet.
+func Pending(ctx context.Context, db *gorm.DB) ([]Migration, error) {
+	return pending(ctx, db, All())
+}
+
+// List returns the state of every migration, known or only recorded in db,
+// ordered by version.
+func List(ctx context.Context, db *gorm.DB) ([]Status, error) {
+	return list(ctx, db, All())
+}
+
+func up(ctx context.Context, db *gorm.DB, all []Migration) ([]Migration, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+	todo, err := pending(ctx, db, all)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range todo {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return todo[:i], fmt.Errorf("migration %s failed: %w", m, err)
+		}
+	}
+	return todo, nil
+}
+
+func down(ctx context.Context, db *gorm.DB, all []Migration, steps int) ([]Migration, error) {
+	if err := ensureTable(ctx, db); err != nil {
+		return nil, err
+	}
+	statuses, err := list(ctx, db, all)
+	if err != nil {
+		return nil, err
+	}
+
+	var rolledBack []Migration
+	for i := len(statuses) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+		if status.Unknown {
+			return rolledBack, fmt.Errorf("cannot roll back %d: %w", status.Version, ErrUnknownMigration)
+		}
+		m := status.Migration
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("rolling back migration %s failed: %w", m, err)
+		}
+		rolledBack = append(rolledBack, m)
+	}
+	return rolledBack, nil
+}
+
+func pending(ctx context.Context, db *gorm.DB, all []Migration) ([]Migration, error) {
+	statuses, err := list(ctx, db, all)
+	if err != nil {
+		return nil, err
+	}
+	var todo []Migration
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			todo = append(todo, status.Migration)
+		}
+	}
+	return todo, nil
+}
+
+func ensureTable(ctx context.Context, db *gorm.DB) error {
+	if err := db.WithContext(ctx).AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("failed to prepare schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// list reads the applied versions without creating schema_migrations, so it
+// is safe to call from health checks; a missing table means nothing has been
+// applied.
+func list(ctx context.Context, db *gorm.DB, all []Migration) ([]Status, error) {
+	db = db.WithContext(ctx)
+	var applied []schemaMigration
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Order("version").Find(&applied).Error; err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+	}
+
+	appliedAt := make(map[int64]time.Time, len(applied))
+	for _, row := range applied {
+		appliedAt[row.Version] = row.AppliedAt
+	}
+	statuses := make([]Status, 0, len(all)+len(applied))
+	known := make(map[int64]bool, len(all))
+	for _, m := range all {
+		known[m.Version] = true
+		status := Status{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		if !known[row.Version] {
+			at := row.AppliedAt
+			statuses = append(statuses, Status{Migration: Migration{Version: row.Version, Name: row.Name}, AppliedAt: &at, Unknown: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// execAll runs statements in order, stopping at the first failure.
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
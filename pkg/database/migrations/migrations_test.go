@@ -0,0 +1,227 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   string `gorm:"primaryKey"`
+	Name string
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.New().String()+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_widgets",
+			Up:      func(tx *gorm.DB) error { return tx.Migrator().CreateTable(&widget{}) },
+			Down:    func(tx *gorm.DB) error { return tx.Migrator().DropTable(&widget{}) },
+		},
+		{
+			Version: 2,
+			Name:    "widgets_name_index",
+			Up:      func(tx *gorm.DB) error { return tx.Exec("CREATE INDEX idx_widgets_name ON widgets (name)").Error },
+			Down:    func(tx *gorm.DB) error { return tx.Exec("DROP INDEX idx_widgets_name").Error },
+		},
+	}
+}
+
+func TestUpDown(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	all := testMigrations()
+
+	todo, err := pending(ctx, db, all)
+	if err != nil || len(todo) != 2 {
+		t.Fatalf("Expected 2 pending migrations, got %v (%v)", todo, err)
+	}
+	if db.Migrator().HasTable(&schemaMigration{}) {
+		t.Error("Expected checking for pending migrations not to create schema_migrations")
+	}
+
+	applied, err := up(ctx, db, all)
+	if err != nil || len(applied) != 2 {
+		t.Fatalf("Expected 2 applied migrations, got %v (%v)", applied, err)
+	}
+	if !db.Migrator().HasIndex(&widget{}, "idx_widgets_name") {
+		t.Error("Expected the index to exist")
+	}
+	applied, err = up(ctx, db, all)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("Expected a second run to apply nothing, got %v (%v)", applied, err)
+	}
+
+	rolledBack, err := down(ctx, db, all, 1)
+	if err != nil || len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Fatalf("Expected migration 2 to be rolled back, got %v (%v)", rolledBack, err)
+	}
+	if db.Migrator().HasIndex(&widget{}, "idx_widgets_name") {
+		t.Error("Expected the index to be dropped")
+	}
+	if !db.Migrator().HasTable(&widget{}) {
+		t.Error("Expected the table to remain")
+	}
+
+	rolledBack, err = down(ctx, db, all, 5)
+	if err != nil || len(rolledBack) != 1 {
+		t.Fatalf("Expected only migration 1 to be left to roll back, got %v (%v)", rolledBack, err)
+	}
+	if db.Migrator().HasTable(&widget{}) {
+		t.Error("Expected the table to be dropped")
+	}
+}
+
+func TestUp_FailedMigrationIsNotRecorded(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	all := append(testMigrations(), Migration{
+		Version: 3,
+		Name:    "broken",
+		Up:      func(tx *gorm.DB) error { return tx.Exec("ALTER TABLE missing ADD COLUMN x TEXT").Error },
+		Down:    func(tx *gorm.DB) error { return nil },
+	})
+
+	applied, err := up(ctx, db, all)
+	if err == nil || !strings.Contains(err.Error(), "3_broken") {
+		t.Fatalf("Expected the broken migration to fail, got %v", err)
+	}
+	if len(applied) != 2 {
+		t.Errorf("Expected the migrations before it to stay applied, got %v", applied)
+	}
+	todo, err := pending(ctx, db, all)
+	if err != nil || len(todo) != 1 || todo[0].Version != 3 {
+		t.Errorf("Expected only the broken migration to be pending, got %v (%v)", todo, err)
+	}
+}
+
+func TestList_UnknownMigration(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	all := testMigrations()
+	if _, err := up(ctx, db, all); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	statuses, err := list(ctx, db, all[:1])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(statuses) != 2 || !statuses[1].Unknown || statuses[1].Name != "widgets_name_index" {
+		t.Fatalf("Expected migration 2 to be listed as unknown, got %+v", statuses)
+	}
+	if _, err := down(ctx, db, all[:1], 1); !errors.Is(err, ErrUnknownMigration) {
+		t.Errorf("Expected ErrUnknownMigration, got %v", err)
+	}
+}
+
+func TestAll_RegisteredMigrationsApply(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if _, err := Up(ctx, db); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if _, err := Down(ctx, db, len(All())); err != nil {
+		t.Fatalf("Failed to roll back: %v", err)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("Expected rolling everything back to drop the tables")
+	}
+	if _, err := Up(ctx, db); err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 18, 9, 30, 15, 0, time.UTC)
+
+	path, err := Create(dir, "Add rentals table!", now)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if filepath.Base(path) != "20261018093015_add_rentals_table.go" {
+		t.Errorf("Unexpected file name %s", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read migration: %v", err)
+	}
+	for _, want := range []string{"Version: 20261018093015", `Name:    "add_rentals_table"`} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Expected the migration to contain %q:\n%s", want, content)
+		}
+	}
+
+	if _, err := Create(dir, "add rentals table", now); err == nil {
+		t.Error("Expected an existing migration not to be overwritten")
+	}
+	if _, err := Create(dir, "!!!", now); err == nil {
+		t.Error("Expected a name without letters or digits to be rejected")
+	}
+}
+
+func TestUserIdentityIndexes_ReportsDuplicates(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if _, err := up(ctx, db, All()[:1]); err != nil {
+		t.Fatalf("Failed to create the initial schema: %v", err)
+	}
+	for _, user := range []initialUser{
+		{ID: "user-1", Username: "alice", Email: "Alice@Example.com", Password: "x"},
+		{ID: "user-2", Username: "bob", Email: " alice@example.com", Password: "x"},
+		{ID: "user-3", Username: "Carol", Email: "carol@example.com", Password: "x"},
+	} {
+		if err := db.Create(&user).Error; err != nil {
+			t.Fatalf("Failed to seed users: %v", err)
+		}
+	}
+
+	_, err := up(ctx, db, All())
+	if err == nil {
+		t.Fatal("Expected the migration to refuse duplicate emails")
+	}
+	for _, want := range []string{"email", `user-1 ("Alice@Example.com")`, `user-2 (" alice@example.com")`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s, got: %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "user-3") {
+		t.Errorf("Expected only the conflicting users to be named, got: %v", err)
+	}
+	var email string
+	db.Table("users").Where("id = ?", "user-1").Select("email").Scan(&email)
+	if email != "Alice@Example.com" {
+		t.Errorf("Expected no user to be changed, got email %q", email)
+	}
+
+	if err := db.Table("users").Where("id = ?", "user-2").Update("email", "bob@example.com").Error; err != nil {
+		t.Fatalf("Failed to fix the duplicate: %v", err)
+	}
+	if _, err := up(ctx, db, All()); err != nil {
+		t.Fatalf("Expected the migration to succeed once the duplicate is fixed, got: %v", err)
+	}
+	var username string
+	db.Table("users").Where("id = ?", "user-3").Select("username").Scan(&username)
+	if username != "carol" {
+		t.Errorf("Expected usernames to be normalized, got %q", username)
+	}
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"movierental/pkg/database/migrations"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Models lists every model whose table the application needs.
+func Models() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&requests.Cart{},
+		&models.AuditLog{},
+		&models.MFARecoveryCode{},
+		&models.PasswordResetToken{},
+		&models.UserIdentity{},
+		&models.APIKey{},
+		&models.Session{},
+		&models.UsedMFAToken{},
+	}
+}
+
+// Ping checks that the database accepts connections.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckMigrations fails when db is missing migrations of this build, so the
+// application never serves against a schema older than its code.
+func CheckMigrations(ctx context.Context, db *gorm.DB) error {
+	pending, err := migrations.Pending(ctx, db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		names := make([]string, len(pending))
+		for i, m := range pending {
+			names[i] = m.String()
+		}
+		return fmt.Errorf("%d pending migrations: %s", len(pending), strings.Join(names, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"movierental/pkg/database/migrations"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCheckMigrations(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:schema_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	ctx := context.Background()
+
+	err = CheckMigrations(ctx, db)
+	if err == nil || !strings.Contains(err.Error(), "create_initial_schema") {
+		t.Fatalf("Expected pending migrations to be reported, got %v", err)
+	}
+
+	if _, err := migrations.Up(ctx, db); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	if err := CheckMigrations(ctx, db); err != nil {
+		t.Errorf("Expected no pending migrations, got %v", err)
+	}
+	if err := Ping(ctx, db); err != nil {
+		t.Errorf("Expected the database to answer, got %v", err)
+	}
+}
+
+// TestMigrations_MatchModels catches model changes that were not given a
+// migration.
+func TestMigrations_MatchModels(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:schema_models_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if _, err := migrations.Up(context.Background(), db); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	migrator := db.Migrator()
+	for _, model := range Models() {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatalf("Failed to parse model %T: %v", model, err)
+		}
+		if !migrator.HasTable(stmt.Schema.Table) {
+			t.Errorf("Table %s of %T is not created by any migration", stmt.Schema.Table, model)
+			continue
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !migrator.HasColumn(model, field.DBName) {
+				t.Errorf("Column %s.%s of %T is not created by any migration", stmt.Schema.Table, field.DBName, model)
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package fixtures loads users and their carts from YAML or JSON files, for
+// demo databases and integration tests. Loading is idempotent: a fixture
+// already in the database is brought up to date instead of duplicated, so
+// the same files can be applied again after they change.
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// idNamespace derives stable IDs for fixtures that do not set one, so a
+// fixture gets the same ID in every database it is loaded into.
+var idNamespace = uuid.MustParse("6f2b8f7e-3c1a-4f4e-9a55-0d7c1f0e5a21")
+
+// Set is the content of a fixture file.
+type Set struct {
+	Users []User `json:"users"`
+}
+
+// User is an account with its cart. Password is the plain password; it is
+// hashed on load.
+type User struct {
+	ID       string                   `json:"id"`
+	Username string                   `json:"username"`
+	Email    string                   `json:"email"`
+	Password string                   `json:"password"`
+	Role     string                   `json:"role"`
+	Cart     []requests.CartMovieItem `json:"cart"`
+}
+
+// Result counts what a load changed.
+type Result struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+// ReadFile parses the fixture file at path, by its extension as JSON or
+// YAML. Unknown fields are rejected so typos do not go unnoticed.
+func ReadFile(path string) (Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Set{}, fmt.Errorf("failed to read fixture file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+	case ".yaml", ".yml":
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return Set{}, fmt.Errorf("failed to parse YAML fixtures %s: %w", path, err)
+		}
+		if data, err = json.Marshal(values); err != nil {
+			return Set{}, fmt.Errorf("failed to convert YAML fixtures %s: %w", path, err)
+		}
+	default:
+		return Set{}, fmt.Errorf("unsupported fixture file extension %q; use .json, .yaml or .yml", ext)
+	}
+
+	var set Set
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&set); err != nil {
+		return Set{}, fmt.Errorf("failed to decode fixtures %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// LoadFiles reads and loads each fixture file in turn.
+func LoadFiles(ctx context.Context, db *gorm.DB, paths ...string) (Result, error) {
+	var total Result
+	for _, path := range paths {
+		set, err := ReadFile(path)
+		if err != nil {
+			return total, err
+		}
+		result, err := Load(ctx, db, set)
+		if err != nil {
+			return total, fmt.Errorf("failed to load fixtures %s: %w", path, err)
+		}
+		total.Created += result.Created
+		total.Updated += result.Updated
+		total.Unchanged += result.Unchanged
+	}
+	return total, nil
+}
+
+// Load writes set to db in one transaction. Users are matched by ID and
+// created with an empty cart if missing; existing users get the username,
+// email, role, password and cart movies of their fixture. A fixture without
+// a password keeps the stored one.
+func Load(ctx context.Context, db *gorm.DB, set Set) (Result, error) {
+	var result Result
+	err := repository.NewStore(db).Transaction(ctx, func(tx *repository.Store) error {
+		result = Result{}
+		for i, fixture := range set.Users {
+			changed, created, err := loadUser(ctx, tx, fixture)
+			if err != nil {
+				return fmt.Errorf("user %d (%s): %w", i+1, fixture.Username, err)
+			}
+			switch {
+			case created:
+				result.Created++
+			case changed:
+				result.Updated++
+			default:
+				result.Unchanged++
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func loadUser(ctx context.Context, tx *repository.Store, fixture User) (changed bool, created bool, err error) {
+	username := utils.NormalizeUsername(fixture.Username)
+	email := utils.NormalizeEmail(fixture.Email)
+	role := fixture.Role
+	if role == "" {
+		role = models.RoleUser
+	}
+	switch {
+	case username == "" || email == "":
+		return false, false, errors.New("username and email are required")
+	case role != models.RoleUser && role != models.RoleStaff && role != models.RoleAdmin:
+		return false, false, fmt.Errorf("unknown role %q", role)
+	}
+	id := fixture.ID
+	if id == "" {
+		id = uuid.NewSHA1(idNamespace, []byte("user:"+username)).String()
+	}
+
+	user, err := tx.Users.FindByID(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		if fixture.Password == "" {
+			return false, false, errors.New("password is required for a new user")
+		}
+		created = true
+		user = models.User{ID: id}
+	case err != nil:
+		return false, false, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if user.Username != username || user.Email != email || user.Role != role {
+		user.Username, user.Email, user.Role = username, email, role
+		changed = true
+	}
+	// Fixture passwords are demo data, so the password policy does not
+	// apply, but they are hashed like any other.
+	if fixture.Password != "" && !utils.CheckPasswordHash(fixture.Password, user.Password) {
+		if user.Password, err = utils.HashPassword(fixture.Password); err != nil {
+			return false, false, fmt.Errorf("failed to hash password: %w", err)
+		}
+		changed = true
+	}
+	if created {
+		err = tx.Users.Create(ctx, &user)
+	} else if changed {
+		err = tx.Users.Save(ctx, &user)
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		return false, false, errors.New("username or email belongs to another user")
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to save user: %w", err)
+	}
+
+	cartChanged, err := loadCart(ctx, tx, user.ID, fixture.Cart)
+	if err != nil {
+		return false, false, err
+	}
+	return changed || cartChanged, created, nil
+}
+
+func loadCart(ctx context.Context, tx *repository.Store, userID string, movies []requests.CartMovieItem) (bool, error) {
+	if movies == nil {
+		movies = []requests.CartMovieItem{}
+	}
+	cart, err := tx.Carts.FindByUserID(ctx, userID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		cart = requests.Cart{Id: uuid.NewSHA1(idNamespace, []byte("cart:"+userID)).String(), UserId: userID, Movies: movies}
+		if err := tx.Carts.Create(ctx, &cart); err != nil {
+			return false, fmt.Errorf("failed to create cart: %w", err)
+		}
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to look up cart: %w", err)
+	}
+
+	if reflect.DeepEqual([]requests.CartMovieItem(cart.Movies), movies) {
+		return false, nil
+	}
+	cart.Movies = movies
+	if err := tx.Carts.Save(ctx, &cart); err != nil {
+		return false, fmt.Errorf("failed to save cart: %w", err)
+	}
+	return true, nil
+}
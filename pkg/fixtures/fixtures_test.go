@@ -0,0 +1,112 @@
+package fixtures
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFiles(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+	ctx := context.Background()
+
+	result, err := LoadFiles(ctx, testDB, "../../fixtures/demo.yaml", "testdata/users.json")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result != (Result{Created: 6}) {
+		t.Errorf("Expected 6 users to be created, got %+v", result)
+	}
+
+	var carol models.User
+	if err := testDB.Where("id = ?", "fixture-carol").First(&carol).Error; err != nil {
+		t.Fatalf("Expected the fixture ID to be used, got: %v", err)
+	}
+	if carol.Username != "carol" || carol.Email != "carol@example.com" || carol.Role != models.RoleUser {
+		t.Errorf("Expected a normalized plain user, got %+v", carol)
+	}
+	if !utils.CheckPasswordHash("Carol-Passw0rd!", carol.Password) {
+		t.Error("Expected the password to be hashed")
+	}
+	var cart requests.Cart
+	testDB.Where("user_id = ?", carol.ID).First(&cart)
+	if len(cart.Movies) != 1 || cart.Movies[0].Title != "Alien" {
+		t.Errorf("Expected the fixture cart, got %+v", cart.Movies)
+	}
+
+	t.Run("Loading again changes nothing", func(t *testing.T) {
+		result, err := LoadFiles(ctx, testDB, "../../fixtures/demo.yaml", "testdata/users.json")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result != (Result{Unchanged: 6}) {
+			t.Errorf("Expected nothing to change, got %+v", result)
+		}
+		var count int64
+		testDB.Model(&models.User{}).Count(&count)
+		if count != 6 {
+			t.Errorf("Expected 6 users, got %d", count)
+		}
+	})
+
+	t.Run("Changed fixtures update the database", func(t *testing.T) {
+		result, err := Load(ctx, testDB, Set{Users: []User{{ID: "fixture-carol", Username: "carol", Email: "carol@example.com", Role: models.RoleAdmin}}})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result != (Result{Updated: 1}) {
+			t.Errorf("Expected one update, got %+v", result)
+		}
+		testDB.Where("id = ?", "fixture-carol").First(&carol)
+		if carol.Role != models.RoleAdmin || !utils.CheckPasswordHash("Carol-Passw0rd!", carol.Password) {
+			t.Errorf("Expected the role to change and the password to stay, got %+v", carol)
+		}
+		testDB.Where("user_id = ?", carol.ID).First(&cart)
+		if len(cart.Movies) != 0 {
+			t.Errorf("Expected the cart to be emptied, got %+v", cart.Movies)
+		}
+	})
+
+	t.Run("A failing fixture rolls back the whole set", func(t *testing.T) {
+		_, err := Load(ctx, testDB, Set{Users: []User{
+			{Username: "erin", Email: "erin@example.com", Password: "Erin-Passw0rd!"},
+			{Username: "frank", Email: "alice@example.com", Password: "Frank-Passw0rd!"},
+		}})
+		if err == nil || !strings.Contains(err.Error(), "frank") {
+			t.Fatalf("Expected the duplicate email to be reported, got: %v", err)
+		}
+		var count int64
+		testDB.Model(&models.User{}).Where("username = ?", "erin").Count(&count)
+		if count != 0 {
+			t.Error("Expected the earlier fixture to be rolled back")
+		}
+	})
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	set, err := ReadFile(write("users.yml", "users:\n  - username: zed\n    email: zed@example.com\n"))
+	if err != nil || len(set.Users) != 1 || set.Users[0].Username != "zed" {
+		t.Errorf("Expected one user from YAML, got %+v (%v)", set, err)
+	}
+	if _, err := ReadFile(write("typo.yaml", "users:\n  - usernme: zed\n")); err == nil {
+		t.Error("Expected an unknown field to be rejected")
+	}
+	if _, err := ReadFile(write("users.toml", "")); err == nil {
+		t.Error("Expected an unsupported extension to be rejected")
+	}
+}
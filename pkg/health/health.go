@@ -0,0 +1,98 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const defaultCheckTimeout = 2 * time.Second
+
+// Check returns an error when its dependency cannot serve requests.
+type Check func(ctx context.Context) error
+
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	// StatusDegraded is reported by a failing informational check.
+	StatusDegraded = "degraded"
+)
+
+// Checker runs a fixed set of named checks concurrently, each bounded by
+// Timeout.
+type Checker struct {
+	Timeout time.Duration
+
+	names         []string
+	checks        map[string]Check
+	informational map[string]bool
+}
+
+func NewChecker() *Checker {
+	return &Checker{Timeout: defaultCheckTimeout, checks: make(map[string]Check), informational: make(map[string]bool)}
+}
+
+func (hc *Checker) Add(name string, check Check) {
+	hc.add(name, check, false)
+}
+
+// AddInformational adds a check for a dependency the instance can serve
+// without. Its failures are reported as degraded but do not fail the run, so
+// a third-party outage does not take every instance out of rotation.
+func (hc *Checker) AddInformational(name string, check Check) {
+	hc.add(name, check, true)
+}
+
+func (hc *Checker) add(name string, check Check, informational bool) {
+	if _, exists := hc.checks[name]; !exists {
+		hc.names = append(hc.names, name)
+	}
+	hc.checks[name] = check
+	hc.informational[name] = informational
+}
+
+// Run executes every check and reports whether all of them passed, with the
+// result of each one.
+func (hc *Checker) Run(ctx context.Context) (bool, map[string]Result) {
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	results := make(map[string]Result, len(hc.names))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range hc.names {
+		wg.Add(1)
+		go func(name string, check Check, informational bool) {
+			defer wg.Done()
+			result := Result{Status: StatusOK}
+			if err := check(ctx); err != nil {
+				result = Result{Status: StatusFail, Error: err.Error()}
+				if informational {
+					result.Status = StatusDegraded
+				}
+			}
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, hc.checks[name], hc.informational[name])
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		if result.Status == StatusFail {
+			healthy = false
+		}
+	}
+	return healthy, results
+}
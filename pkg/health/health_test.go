@@ -0,0 +1,58 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChecker_Run(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+
+	healthy, results := checker.Run(context.Background())
+	if !healthy || results["database"].Status != StatusOK {
+		t.Fatalf("Expected a healthy result, got %v %+v", healthy, results)
+	}
+
+	checker.Add("movie_api", func(ctx context.Context) error { return errors.New("circuit open") })
+	healthy, results = checker.Run(context.Background())
+	if healthy {
+		t.Error("Expected a failing check to make the result unhealthy")
+	}
+	if results["movie_api"].Status != StatusFail || results["movie_api"].Error != "circuit open" {
+		t.Errorf("Expected the failure to be reported, got %+v", results["movie_api"])
+	}
+	if results["database"].Status != StatusOK {
+		t.Errorf("Expected other checks to still pass, got %+v", results["database"])
+	}
+}
+
+func TestChecker_RunReportsInformationalChecks(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("database", func(ctx context.Context) error { return nil })
+	checker.AddInformational("movie_api", func(ctx context.Context) error { return errors.New("circuit open") })
+
+	healthy, results := checker.Run(context.Background())
+	if !healthy {
+		t.Error("Expected a failing informational check not to make the result unhealthy")
+	}
+	if results["movie_api"].Status != StatusDegraded || results["movie_api"].Error != "circuit open" {
+		t.Errorf("Expected the failure to be reported as degraded, got %+v", results["movie_api"])
+	}
+}
+
+func TestChecker_RunTimesOutSlowChecks(t *testing.T) {
+	checker := NewChecker()
+	checker.Timeout = 10 * time.Millisecond
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	healthy, results := checker.Run(context.Background())
+	if healthy || results["slow"].Status != StatusFail {
+		t.Errorf("Expected the slow check to fail, got %+v", results["slow"])
+	}
+}
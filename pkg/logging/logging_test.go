@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+	return line
+}
+
+func TestNew_RedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.Info("Connecting",
+		"user", "postgres",
+		"password", "hunter2",
+		slog.Group("headers", "Authorization", "Bearer abc", "X-RapidAPI-Key", "key"),
+		"refresh_token", "rt",
+	)
+
+	line := decodeLine(t, &buf)
+	if line["user"] != "postgres" {
+		t.Errorf("Expected ordinary fields to be kept, got %v", line["user"])
+	}
+	for _, value := range []interface{}{line["password"], line["refresh_token"]} {
+		if value != redacted {
+			t.Errorf("Expected secret to be redacted, got %v", value)
+		}
+	}
+	headers, _ := line["headers"].(map[string]interface{})
+	if headers["Authorization"] != redacted || headers["X-RapidAPI-Key"] != redacted {
+		t.Errorf("Expected secrets inside groups to be redacted, got %v", headers)
+	}
+}
+
+func TestNew_AddsRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "Handled")
+
+	if line := decodeLine(t, &buf); line["request_id"] != "req-1" {
+		t.Errorf("Expected the request ID from the context, got %v", line["request_id"])
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "error": slog.LevelError, "": slog.LevelInfo}
+	for input, want := range cases {
+		if got := ParseLevel(input); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeyParts are matched against lower-cased attribute keys. Values of
+// matching attributes never reach the output.
+var sensitiveKeyParts = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"api_key",
+	"apikey",
+	"x-rapidapi-key",
+	"authorization",
+	"cookie",
+	"dsn",
+	"private_key",
+	"recovery_code",
+	"totp",
+}
+
+// Redact replaces the values of attributes whose key names a secret. It is
+// used as the ReplaceAttr function of the JSON handler, so it applies to
+// attributes inside groups too.
+func Redact(groups []string, attr slog.Attr) slog.Attr {
+	if attr.Value.Kind() == slog.KindGroup {
+		return attr
+	}
+	if IsSensitiveKey(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	return attr
+}
+
+// IsSensitiveKey reports whether key, e.g. a log attribute or a config field,
+// names a secret.
+func IsSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+const queryStartKey = "metrics:query_start"
+
+// InstrumentDB times every statement GORM issues on db and exports the
+// connection pool statistics.
+func InstrumentDB(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		operation string
+		before    error
+		after     error
+	}{
+		{"create",
+			callbacks.Create().Before("gorm:create").Register("metrics:before_create", startQueryTimer),
+			callbacks.Create().After("gorm:create").Register("metrics:after_create", observeQuery("create"))},
+		{"query",
+			callbacks.Query().Before("gorm:query").Register("metrics:before_query", startQueryTimer),
+			callbacks.Query().After("gorm:query").Register("metrics:after_query", observeQuery("query"))},
+		{"update",
+			callbacks.Update().Before("gorm:update").Register("metrics:before_update", startQueryTimer),
+			callbacks.Update().After("gorm:update").Register("metrics:after_update", observeQuery("update"))},
+		{"delete",
+			callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", startQueryTimer),
+			callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", observeQuery("delete"))},
+		{"row",
+			callbacks.Row().Before("gorm:row").Register("metrics:before_row", startQueryTimer),
+			callbacks.Row().After("gorm:row").Register("metrics:after_row", observeQuery("row"))},
+		{"raw",
+			callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", startQueryTimer),
+			callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", observeQuery("raw"))},
+	}
+	for _, r := range registrations {
+		if err := errors.Join(r.before, r.after); err != nil {
+			return fmt.Errorf("failed to register %s metrics callbacks: %w", r.operation, err)
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	err = Registry.Register(collectors.NewDBStatsCollector(sqlDB, db.Name()))
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if err != nil && !errors.As(err, &alreadyRegistered) {
+		return fmt.Errorf("failed to register database pool metrics: %w", err)
+	}
+	return nil
+}
+
+func startQueryTimer(tx *gorm.DB) {
+	tx.InstanceSet(queryStartKey, time.Now())
+}
+
+func observeQuery(operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(queryStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		DBQueryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			DBQueryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
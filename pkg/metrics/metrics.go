@@ -0,0 +1,164 @@
+// Package metrics defines the application's Prometheus metrics and serves
+// them in the text exposition format.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "movierental"
+
+// Registry holds every metric of the application together with the Go
+// runtime and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent handling HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Time spent in database statements issued through GORM, by operation and table.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"operation", "table"})
+
+	DBQueryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Database statements that failed, by operation and table. Lookups finding no record are not errors.",
+	}, []string{"operation", "table"})
+
+	UpstreamRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of calls to the movie provider, by path and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"path", "outcome"})
+
+	UpstreamErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed calls to the movie provider, by path and reason.",
+	}, []string{"path", "reason"})
+
+	UpstreamSharedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_shared_requests_total",
+		Help:      "Movie provider calls answered by an identical call already in flight, by path.",
+	}, []string{"path"})
+
+	UpstreamQuotaRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_quota_remaining",
+		Help:      "Movie provider calls left, by window: our daily and monthly budgets, and the provider's own limit.",
+	}, []string{"window"})
+
+	UpstreamCacheOnly = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_cache_only",
+		Help:      "1 while the movie provider budget is nearly spent and only cached responses are served.",
+	})
+
+	CacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	Signups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signups_total",
+		Help:      "Accounts created, by method.",
+	}, []string{"method"})
+
+	Logins = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Completed logins, counted when a session starts.",
+	})
+
+	LoginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Rejected login attempts, by reason.",
+	}, []string{"reason"})
+
+	CartAdds = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cart_adds_total",
+		Help:      "Movies added to carts.",
+	})
+
+	RateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter, by route group.",
+	}, []string{"group"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		DBQueryDuration,
+		DBQueryErrors,
+		UpstreamRequestDuration,
+		UpstreamErrors,
+		UpstreamSharedRequests,
+		CacheRequests,
+		Signups,
+		Logins,
+		LoginFailures,
+		CartAdds,
+		RateLimited,
+		UpstreamQuotaRemaining,
+		UpstreamCacheOnly,
+	)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewServeMux serves Handler at /metrics. With a bearerToken, scrapes must
+// send it in the Authorization header.
+func NewServeMux(bearerToken string) *http.ServeMux {
+	handler := Handler()
+	if bearerToken != "" {
+		handler = requireBearerToken(bearerToken, handler)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", handler)
+	return mux
+}
+
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"movierental/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	before := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/items/:id", "204"))
+	for _, path := range []string{"/items/1", "/items/2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "/items/:id", "204")) - before; got != 2 {
+		t.Errorf("Expected 2 requests counted for the route, got %v", got)
+	}
+	if got := testutil.ToFloat64(HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "404")); got < 1 {
+		t.Errorf("Expected the unmatched request to be counted, got %v", got)
+	}
+}
+
+func TestInstrumentDB(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	if err := InstrumentDB(testDB); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var users []models.User
+	if err := testDB.WithContext(context.Background()).Find(&users).Error; err != nil {
+		t.Fatalf("Failed to query users: %v", err)
+	}
+
+	body := httptest.NewRecorder()
+	Handler().ServeHTTP(body, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`movierental_db_query_duration_seconds_count{operation="query",table="users"}`,
+		"go_sql_open_connections",
+	} {
+		if !strings.Contains(body.Body.String(), want) {
+			t.Errorf("Expected exposition to contain %q", want)
+		}
+	}
+}
+
+func TestNewServeMux_RequiresBearerToken(t *testing.T) {
+	mux := NewServeMux("scrape-secret")
+	for _, tc := range []struct {
+		authorization string
+		want          int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer scrape-secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tc.authorization != "" {
+			req.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("Authorization %q: expected status %d, got %d", tc.authorization, tc.want, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	NewServeMux("").ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected metrics without a token to be open on their listener, got %d", w.Code)
+	}
+}
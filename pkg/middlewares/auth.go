@@ -1,21 +1,83 @@
 package middlewares
 
 import (
+	"context"
+	"errors"
 	"movierental/pkg/utils"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func Authenticate(c *gin.Context) {
-	token := c.Request.Header.Get("Authorization")
+const (
+	mfaEnrollmentPathPrefix = "/me/mfa"
 
-	userId, err := utils.VerifyToken(token)
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
-		return
+	APIKeyHeader = "X-API-Key"
+
+	AuthMethodToken  = "token"
+	AuthMethodAPIKey = "api_key"
+)
+
+// APIKeyVerifier resolves an API key to the claims of its owner.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, rawKey string) (utils.TokenClaims, error)
+}
+
+// SessionVerifier reports whether the session an access token belongs to is
+// still active.
+type SessionVerifier interface {
+	VerifySession(ctx context.Context, sessionId string) error
+}
+
+// Authenticate accepts either a bearer access token in the Authorization
+// header or, when apiKeys is set, an API key in the X-API-Key header. Access
+// tokens must belong to a session; when sessions is set, tokens whose session
+// has been terminated are rejected.
+func Authenticate(apiKeys APIKeyVerifier, sessions SessionVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var claims utils.TokenClaims
+		var err error
+		authMethod := AuthMethodToken
+
+		if rawKey := c.Request.Header.Get(APIKeyHeader); rawKey != "" && apiKeys != nil {
+			authMethod = AuthMethodAPIKey
+			claims, err = apiKeys.VerifyAPIKey(c.Request.Context(), rawKey)
+		} else {
+			claims, err = utils.ParseAccessToken(c.Request.Header.Get("Authorization"))
+			if err == nil && claims.SessionID == "" {
+				err = errors.New("token is not bound to a session")
+			}
+		}
+		if err == nil && authMethod == AuthMethodToken && sessions != nil {
+			err = sessions.VerifySession(c.Request.Context(), claims.SessionID)
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+
+		if claims.MFAEnrollmentRequired && !strings.HasPrefix(c.FullPath(), mfaEnrollmentPathPrefix) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "MFA enrollment is required for your role. Enroll via /me/mfa/totp first"})
+			return
+		}
+
+		c.Set("userId", claims.UserID)
+		c.Set("role", claims.Role)
+		c.Set("authMethod", authMethod)
+		c.Set("scopes", claims.Scopes)
+		c.Set("sessionId", claims.SessionID)
+		c.Next()
 	}
+}
 
-	c.Set("userId", userId)
+// RejectAPIKeys restricts a route to interactive access tokens, so that a
+// leaked API key cannot be used to mint or manage other credentials. It must
+// run after Authenticate.
+func RejectAPIKeys(c *gin.Context) {
+	if c.GetString("authMethod") == AuthMethodAPIKey {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "this endpoint requires a bearer token; API keys are not accepted"})
+		return
+	}
 	c.Next()
 }
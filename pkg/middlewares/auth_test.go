@@ -0,0 +1,59 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"movierental/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	utils.ConfigureTestSigningKey()
+	os.Exit(m.Run())
+}
+
+type activeSessions map[string]bool
+
+func (s activeSessions) VerifySession(ctx context.Context, sessionId string) error {
+	if !s[sessionId] {
+		return errors.New("session has been terminated")
+	}
+	return nil
+}
+
+func TestAuthenticate_RequiresActiveSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/me", Authenticate(nil, activeSessions{"active": true}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name      string
+		sessionId string
+		want      int
+	}{
+		{"active session", "active", http.StatusOK},
+		{"terminated session", "revoked", http.StatusUnauthorized},
+		{"no session", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := utils.GenerateAccessToken(utils.TokenClaims{UserID: "user123", Role: "user", SessionID: tt.sessionId})
+			if err != nil {
+				t.Fatalf("GenerateAccessToken failed: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
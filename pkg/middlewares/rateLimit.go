@@ -0,0 +1,71 @@
+package middlewares
+
+import (
+	"log/slog"
+	"math"
+	"movierental/config"
+	"movierental/pkg/metrics"
+	"movierental/pkg/ratelimit"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimiter applies the configured rate limits of each route group.
+type RateLimiter struct {
+	store ratelimit.Store
+	cfg   config.RateLimitConfig
+	now   func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter for cfg keeping its buckets in store.
+func NewRateLimiter(cfg config.RateLimitConfig, store ratelimit.Store) *RateLimiter {
+	return &RateLimiter{store: store, cfg: cfg, now: time.Now}
+}
+
+// Limit rate limits the routes of group. Clients are told their allowance in
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers, and
+// rejected requests get 429 with Retry-After. Requests are counted per user
+// once authenticated, so Limit belongs after Authenticate on protected
+// routes, and per client IP otherwise.
+//
+// If the store fails the request is let through: losing rate limiting for a
+// while is better than refusing all traffic.
+func (rl *RateLimiter) Limit(group string) gin.HandlerFunc {
+	rule, ok := rl.cfg.Groups[group]
+	if !rl.cfg.Enabled || !ok || rule.RequestsPerMinute <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	limit := ratelimit.PerMinute(rule.RequestsPerMinute, rule.Burst)
+
+	return func(c *gin.Context) {
+		key := group + ":ip:" + c.ClientIP()
+		if userId, ok := c.Get("userId"); ok {
+			key = group + ":user:" + userId.(string)
+		}
+
+		decision, err := rl.store.Take(c.Request.Context(), key, limit, rl.now())
+		if err != nil {
+			slog.ErrorContext(c.Request.Context(), "Rate limit store failed, allowing request", "group", group, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.ResetAfter)))
+		if !decision.Allowed {
+			metrics.RateLimited.WithLabelValues(group).Inc()
+			c.Header("Retry-After", strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "too many requests. Please slow down and try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"movierental/config"
+	"movierental/pkg/ratelimit"
+	"movierental/pkg/server"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimiter_IgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router, err := server.NewRouter(config.ServerConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	limiter := NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Groups:  map[string]config.RateLimitRule{"auth": {RequestsPerMinute: 1, Burst: 1}},
+	}, ratelimit.NewMemoryStore())
+	router.POST("/login", limiter.Limit("auth"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for i, forwardedFor := range []string{"198.51.100.1", "198.51.100.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "203.0.113.7:4000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		want := http.StatusOK
+		if i > 0 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Errorf("Request %d with X-Forwarded-For %s: expected status %d, got %d", i+1, forwardedFor, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package middlewares
+
+import (
+	"movierental/pkg/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireScope allows the request through only when the authenticated token
+// or API key grants scope. It must run after Authenticate.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, _ := c.Get("scopes")
+		granted, _ := scopes.([]string)
+		if !utils.HasScope(granted, scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope", "required_scope": scope})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"context"
+	"movierental/config"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout gives each request a deadline from cfg, so database queries
+// and upstream calls made on its behalf are cancelled once it passes or the
+// client goes away. A timeout of zero leaves the request unbounded.
+func RequestTimeout(cfg config.RequestTimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		seconds := cfg.DefaultSeconds
+		if routeSeconds, ok := cfg.Routes[c.Request.Method+" "+c.FullPath()]; ok {
+			seconds = routeSeconds
+		}
+		if seconds <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(seconds)*time.Second)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// APIKey is a long-lived credential for service-to-service and partner
+// access. Only the hash of the key is stored; Prefix is the non-secret part
+// shown to the owner to tell keys apart.
+type APIKey struct {
+	ID         string `gorm:"primaryKey"`
+	UserID     string `gorm:"not null;index"`
+	Name       string `gorm:"not null"`
+	Prefix     string `gorm:"not null;uniqueIndex"`
+	KeyHash    string `gorm:"not null;uniqueIndex"`
+	Scopes     string `gorm:"not null;default:''"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type AuditLog struct {
+	ID         string    `gorm:"primaryKey" json:"id"`
+	Event      string    `gorm:"not null;index" json:"event"`
+	UserID     string    `gorm:"index" json:"user_id"`
+	Identifier string    `json:"identifier"`
+	IPAddress  string    `json:"ip_address"`
+	Detail     string    `json:"detail"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
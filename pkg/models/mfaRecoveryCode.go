@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type MFARecoveryCode struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
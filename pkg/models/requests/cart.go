@@ -1,7 +1,25 @@
 package requests
 
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
 type Cart struct {
-	Id     string          `gorm:"primaryKey"`
-	UserId string          `json:"user_id"`
-	Movies []CartMovieItem `json:"movies" gorm:"type:json;serializer:json"`
+	Id     string     `gorm:"primaryKey"`
+	UserId string     `json:"user_id"`
+	Movies CartMovies `json:"movies" gorm:"serializer:json"`
+}
+
+// CartMovies is stored as a JSON document in a single column.
+type CartMovies []CartMovieItem
+
+// GormDBDataType picks the column type for the dialect: PostgreSQL's json,
+// and text on SQLite, which has no JSON type and would otherwise give the
+// column numeric affinity.
+func (CartMovies) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "text"
+	}
+	return "json"
 }
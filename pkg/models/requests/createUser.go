@@ -1,7 +1,7 @@
 package requests
 
 type CreateUser struct {
-	Username string `json:"username" binding:"required"`
+	Username string `json:"username" binding:"required,excludes=@"`
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
@@ -0,0 +1,22 @@
+package requests
+
+import "strings"
+
+// Login accepts either a generic identifier or an explicit email/username,
+// so existing clients that post {"email", "password"} keep working.
+type Login struct {
+	Identifier string `json:"identifier"`
+	Email      string `json:"email"`
+	Username   string `json:"username"`
+	Password   string `json:"password" binding:"required"`
+}
+
+// LoginIdentifier returns the first non-empty identifier supplied.
+func (l Login) LoginIdentifier() string {
+	for _, candidate := range []string{l.Identifier, l.Email, l.Username} {
+		if trimmed := strings.TrimSpace(candidate); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
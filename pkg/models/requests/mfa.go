@@ -0,0 +1,10 @@
+package requests
+
+type VerifyTOTP struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFALogin struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
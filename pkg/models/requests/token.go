@@ -0,0 +1,6 @@
+package requests
+
+type CreateScopedToken struct {
+	Scopes           []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInMinutes int      `json:"expires_in_minutes" binding:"omitempty,min=1,max=1440"`
+}
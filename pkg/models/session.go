@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. Access tokens carry the session ID, and
+// the session's refresh token, stored hashed, is rotated on every refresh.
+type Session struct {
+	ID               string `gorm:"primaryKey"`
+	UserID           string `gorm:"not null;index"`
+	RefreshTokenHash string `gorm:"not null;uniqueIndex"`
+	UserAgent        string
+	IPAddress        string
+	CreatedAt        time.Time
+	LastSeenAt       time.Time
+	ExpiresAt        time.Time `gorm:"not null"`
+	RevokedAt        *time.Time
+}
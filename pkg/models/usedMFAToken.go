@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// UsedMFAToken records an MFA token that completed a login, so it cannot
+// complete another. Rows are only needed until the token expires.
+type UsedMFAToken struct {
+	ID        string    `gorm:"primaryKey"`
+	UserID    string    `gorm:"not null;index"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	UsedAt    time.Time `gorm:"not null"`
+}
@@ -1,8 +1,18 @@
 package models
 
+const (
+	RoleUser  = "user"
+	RoleStaff = "staff"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID       string `gorm:"primaryKey"`
-	Username string `gorm:"unique;not null"`
-	Email    string `gorm:"unique;not null"`
-	Password string `gorm:"not null"`
+	ID              string `gorm:"primaryKey"`
+	Username        string `gorm:"unique;not null"`
+	Email           string `gorm:"unique;not null"`
+	Password        string `gorm:"not null"`
+	Role            string `gorm:"not null;default:user"`
+	TOTPSecret      string
+	TOTPEnabled     bool  `gorm:"not null;default:false"`
+	TOTPLastCounter int64 `gorm:"not null;default:0"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserIdentity links a User to an account at an external OpenID Connect
+// provider. A (provider, subject) pair identifies exactly one local user.
+type UserIdentity struct {
+	ID          string `gorm:"primaryKey"`
+	UserID      string `gorm:"not null;index"`
+	Provider    string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
@@ -0,0 +1,93 @@
+package movieExternalApi
+
+import (
+	"movierental/config"
+	"movierental/pkg/metrics"
+	"sync"
+	"time"
+)
+
+const cacheMetricsName = "movie_api"
+
+// ResponseCache keeps successful provider responses for a while, so repeated
+// listings and lookups of the same movie are answered without a call.
+type ResponseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+	now        func() time.Time
+}
+
+type cacheEntry struct {
+	body      []byte
+	expiresAt time.Time
+}
+
+// NewResponseCache returns a cache configured by cfg, or nil when caching is
+// disabled.
+func NewResponseCache(cfg config.MovieCacheConfig) *ResponseCache {
+	if cfg.TTLSeconds <= 0 {
+		return nil
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	return &ResponseCache{
+		ttl:        time.Duration(cfg.TTLSeconds) * time.Second,
+		maxEntries: cfg.MaxEntries,
+		entries:    make(map[string]cacheEntry),
+		now:        time.Now,
+	}
+}
+
+// Get returns the response body stored for key, if it has not expired.
+// Expired entries stay until the cache needs room, for GetStale.
+func (c *ResponseCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok && c.now().Before(entry.expiresAt) {
+		metrics.CacheRequests.WithLabelValues(cacheMetricsName, "hit").Inc()
+		return entry.body, true
+	}
+	metrics.CacheRequests.WithLabelValues(cacheMetricsName, "miss").Inc()
+	return nil, false
+}
+
+// GetStale returns the response body stored for key even if it has expired.
+// It is the fallback when the provider must not be called.
+func (c *ResponseCache) GetStale(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok {
+		metrics.CacheRequests.WithLabelValues(cacheMetricsName, "stale_hit").Inc()
+	}
+	return entry.body, ok
+}
+
+// Set stores body for key. When the cache is full, expired entries are
+// dropped first and then an arbitrary one.
+func (c *ResponseCache) Set(key string, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = cacheEntry{body: body, expiresAt: now.Add(c.ttl)}
+}
@@ -0,0 +1,95 @@
+package movieExternalApi
+
+import (
+	"context"
+	"movierental/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	if NewResponseCache(config.MovieCacheConfig{}) != nil {
+		t.Fatal("Expected no cache without a TTL")
+	}
+
+	now := time.Now()
+	cache := NewResponseCache(config.MovieCacheConfig{TTLSeconds: 60, MaxEntries: 2})
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", []byte("1"))
+	if body, ok := cache.Get("a"); !ok || string(body) != "1" {
+		t.Fatalf("Expected a hit, got %q %v", body, ok)
+	}
+
+	cache.Set("b", []byte("2"))
+	cache.Set("c", []byte("3"))
+	if len(cache.entries) != 2 {
+		t.Errorf("Expected the cache to stay at 2 entries, got %d", len(cache.entries))
+	}
+
+	now = now.Add(61 * time.Second)
+	if _, ok := cache.Get("c"); ok {
+		t.Error("Expected expired entries to miss")
+	}
+}
+
+func TestAPIClient_GetUsesCache(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(config.MovieCacheConfig{TTLSeconds: 60})
+	client := NewAPIClient(server.URL, nil, nil, cache, nil)
+
+	for i := 0; i < 2; i++ {
+		var result map[string]string
+		if err := client.Get(context.Background(), "/movie_details.json", map[string]string{"movie_id": "1"}, &result); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if result["status"] != "ok" {
+			t.Errorf("Expected the decoded response, got %v", result)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected one provider call, got %d", calls.Load())
+	}
+
+	var result map[string]string
+	if err := client.Get(context.Background(), "/movie_details.json", map[string]string{"movie_id": "2"}, &result); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected a different movie to reach the provider, got %d calls", calls.Load())
+	}
+}
+
+func TestAPIClient_GetDoesNotCacheErrorPayloads(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"status":"error","status_message":"Invalid movie ID"}`))
+	}))
+	defer server.Close()
+
+	cache := NewResponseCache(config.MovieCacheConfig{TTLSeconds: 60})
+	client := NewAPIClient(server.URL, nil, nil, cache, nil)
+
+	for i := 0; i < 2; i++ {
+		var result map[string]string
+		client.Get(context.Background(), "/movie_details.json", map[string]string{"movie_id": "1"}, &result)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected every request to reach the provider, got %d calls", calls.Load())
+	}
+	if _, ok := cache.GetStale(server.URL + "/movie_details.json?movie_id=1"); ok {
+		t.Error("Expected the error payload not to be kept for cache-only mode")
+	}
+}
@@ -0,0 +1,109 @@
+package movieExternalApi
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"sync"
+	"time"
+)
+
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+var ErrCircuitOpen = errors.New("movie provider circuit is open")
+
+// CircuitBreaker stops calls to the movie provider after repeated failures,
+// so requests fail fast instead of each waiting for the upstream timeout.
+// Once the open period has passed, a single trial call decides whether the
+// circuit closes again.
+type CircuitBreaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openDuration     time.Duration
+	state            CircuitState
+	failures         int
+	openedAt         time.Time
+	trialInFlight    bool
+	now              func() time.Time
+}
+
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenSeconds <= 0 {
+		cfg.OpenSeconds = 30
+	}
+	return &CircuitBreaker{
+		failureThreshold: cfg.FailureThreshold,
+		openDuration:     time.Duration(cfg.OpenSeconds) * time.Second,
+		state:            CircuitClosed,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may be made, returning ErrCircuitOpen if not.
+// Every allowed call must be followed by Record.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.trialInFlight {
+			return ErrCircuitOpen
+		}
+		b.state = CircuitHalfOpen
+		b.trialInFlight = true
+	}
+	return nil
+}
+
+// Record updates the breaker with the outcome of an allowed call.
+func (b *CircuitBreaker) Record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trialInFlight = false
+	if success {
+		b.state = CircuitClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.failureThreshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// currentState moves an open circuit to half-open once the open period has
+// passed. The caller must hold b.mu.
+func (b *CircuitBreaker) currentState() CircuitState {
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.openDuration {
+		b.state = CircuitHalfOpen
+	}
+	return b.state
+}
+
+// Check fails while the circuit is open, for use as a readiness check.
+func (b *CircuitBreaker) Check(ctx context.Context) error {
+	if b.State() == CircuitOpen {
+		return ErrCircuitOpen
+	}
+	return nil
+}
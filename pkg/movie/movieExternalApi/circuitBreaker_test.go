@@ -0,0 +1,81 @@
+package movieExternalApi
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	breaker := NewCircuitBreaker(config.CircuitBreakerConfig{FailureThreshold: 2, OpenSeconds: 30})
+	breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if err := breaker.Allow(); err != nil {
+			t.Fatalf("Expected call %d to be allowed, got %v", i+1, err)
+		}
+		breaker.Record(false)
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected the circuit to open after the threshold, got %s", breaker.State())
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen while open, got %v", err)
+	}
+	if err := breaker.Check(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the readiness check to fail while open, got %v", err)
+	}
+
+	now = now.Add(31 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the open period, got %v", err)
+	}
+	if err := breaker.Allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected only one trial call at a time, got %v", err)
+	}
+	breaker.Record(false)
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected a failed trial to reopen the circuit, got %s", breaker.State())
+	}
+
+	now = now.Add(31 * time.Second)
+	if err := breaker.Allow(); err != nil {
+		t.Fatalf("Expected a trial call after the open period, got %v", err)
+	}
+	breaker.Record(true)
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected a successful trial to close the circuit, got %s", breaker.State())
+	}
+}
+
+func TestAPIClient_GetRecordsProviderFailures(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	breaker := NewCircuitBreaker(config.CircuitBreakerConfig{FailureThreshold: 1})
+	client := NewAPIClient(server.URL, nil, breaker, nil, nil)
+
+	if err := client.Get(context.Background(), "/movie_details.json", nil, nil); err == nil {
+		t.Fatal("Expected an error for a 400 response")
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected client errors to leave the circuit closed, got %s", breaker.State())
+	}
+
+	status = http.StatusBadGateway
+	if err := client.Get(context.Background(), "/movie_details.json", nil, nil); err == nil {
+		t.Fatal("Expected an error for a 502 response")
+	}
+	if err := client.Get(context.Background(), "/movie_details.json", nil, nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen after a provider failure, got %v", err)
+	}
+}
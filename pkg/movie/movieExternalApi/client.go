@@ -1,34 +1,88 @@
 package movieExternalApi
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"movierental/config"
+	"movierental/pkg/logging"
+	"movierental/pkg/metrics"
 	"net/http"
 	"net/url"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
 )
 
 type APIClientInterface interface {
-	Get(path string, queryParams map[string]string, result interface{}) error
+	Get(ctx context.Context, path string, queryParams map[string]string, result interface{}) error
 }
 
 type APIClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
+	// Breaker, when set, fails calls fast while the provider is down.
+	Breaker *CircuitBreaker
+	// Cache, when set, answers repeated requests without calling the provider.
+	Cache *ResponseCache
+	// Quota, when set, counts calls against the provider budget and stops
+	// them, falling back to stale cached responses, once it is nearly spent.
+	Quota *Quota
+
+	// inflight collapses identical concurrent calls into one.
+	inflight singleflight.Group
+}
+
+// StatusError is returned when the provider answers with a non-2xx status.
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned non-success status: %d %s, Body: %s", e.StatusCode, e.Status, e.Body)
 }
 
-func NewAPIClient(baseURL string) APIClientInterface {
+// NewAPIClient returns a client for the provider at baseURL. A nil
+// httpClient is replaced by NewHTTPClient with the default settings.
+func NewAPIClient(baseURL string, httpClient *http.Client, breaker *CircuitBreaker, cache *ResponseCache, quota *Quota) APIClientInterface {
+	if httpClient == nil {
+		httpClient = NewHTTPClient(config.MovieTransportConfig{})
+	}
 	return &APIClient{
-		BaseURL: baseURL,
-		HTTPClient: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		BaseURL:    baseURL,
+		HTTPClient: httpClient,
+		Breaker:    breaker,
+		Cache:      cache,
+		Quota:      quota,
 	}
 }
 
-func (c *APIClient) Get(path string, queryParams map[string]string, result interface{}) error {
+// Get calls the provider at path and decodes the JSON response into result.
+// The call is traced as a client span and the trace context is passed to
+// the provider in the traceparent header.
+func (c *APIClient) Get(ctx context.Context, path string, queryParams map[string]string, result interface{}) (err error) {
+	ctx, span := otel.Tracer("movierental").Start(ctx, "GET "+path,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(http.MethodGet), semconv.URLPath(path)),
+	)
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}()
+
 	fullURL, err := url.Parse(c.BaseURL + path)
 	if err != nil {
 		return fmt.Errorf("failed to parse URL: %w", err)
@@ -42,44 +96,181 @@ func (c *APIClient) Get(path string, queryParams map[string]string, result inter
 		fullURL.RawQuery = params.Encode()
 	}
 
-	req, err := http.NewRequest(http.MethodGet, fullURL.String(), nil)
+	cacheKey := fullURL.String()
+	if c.Cache != nil {
+		body, ok := c.Cache.Get(cacheKey)
+		span.SetAttributes(attribute.Bool("cache.hit", ok))
+		if ok {
+			return decodeResponse(body, result)
+		}
+	}
+	if c.Quota != nil {
+		if err := c.Quota.Allow(); err != nil {
+			metrics.UpstreamErrors.WithLabelValues(path, "quota_exhausted").Inc()
+			if c.Cache != nil {
+				if body, ok := c.Cache.GetStale(cacheKey); ok {
+					span.SetAttributes(attribute.Bool("cache.stale", true))
+					return decodeResponse(body, result)
+				}
+			}
+			return err
+		}
+	}
+
+	body, err := c.fetch(ctx, path, cacheKey)
 	if err != nil {
-		return fmt.Errorf("error creating GET request: %w", err)
+		return err
 	}
+	return decodeResponse(body, result)
+}
 
-	req.Header.Set("X-RapidAPI-Host", config.AppConfig.MovieAPI.Headers.RapidAPIHost)
-	req.Header.Set("X-RapidAPI-Key", config.AppConfig.MovieAPI.Headers.RapidAPIKey)
-	req.Header.Set("Accept", "application/json")
+// fetch calls the provider for fullURL. Identical calls already in flight
+// share the first one's response instead of calling again; that call runs
+// detached from its caller's cancellation, so a caller giving up does not
+// fail the others waiting on it, but it keeps the caller's deadline.
+func (c *APIClient) fetch(ctx context.Context, path string, fullURL string) ([]byte, error) {
+	leader := false
+	results := c.inflight.DoChan(fullURL, func() (interface{}, error) {
+		leader = true
+		callCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithDeadline(callCtx, deadline)
+			defer cancel()
+		}
+		req, err := http.NewRequestWithContext(callCtx, http.MethodGet, fullURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating GET request: %w", err)
+		}
 
-	return c.doRequest(req, result)
+		req.Header.Set("X-RapidAPI-Host", config.AppConfig.MovieAPI.Headers.RapidAPIHost)
+		req.Header.Set("X-RapidAPI-Key", config.AppConfig.MovieAPI.Headers.RapidAPIKey)
+		req.Header.Set("Accept", "application/json")
+		if requestID := logging.RequestID(ctx); requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+		body, err := c.call(req, path)
+		if err == nil && c.Cache != nil && cacheable(body) {
+			c.Cache.Set(fullURL, body)
+		}
+		return body, err
+	})
+
+	select {
+	case res := <-results:
+		shared := res.Shared && !leader
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("singleflight.shared", shared))
+		if shared {
+			metrics.UpstreamSharedRequests.WithLabelValues(path).Inc()
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		body, _ := res.Val.([]byte)
+		return body, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// cacheable reports whether body is a successful provider payload. The
+// provider reports some errors with a 200 and "status": "error", which must
+// not be served from the cache.
+func cacheable(body []byte) bool {
+	var payload struct {
+		Status string `json:"status"`
+	}
+	return len(body) > 0 && json.Unmarshal(body, &payload) == nil && payload.Status == "ok"
+}
+
+// call sends req through the circuit breaker, if any, and records its
+// latency and outcome under path.
+func (c *APIClient) call(req *http.Request, path string) ([]byte, error) {
+	if c.Breaker != nil {
+		if err := c.Breaker.Allow(); err != nil {
+			metrics.UpstreamErrors.WithLabelValues(path, "circuit_open").Inc()
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+	body, err := c.doRequest(req)
+	if c.Breaker != nil {
+		c.Breaker.Record(!isProviderFailure(err))
+	}
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		metrics.UpstreamErrors.WithLabelValues(path, failureReason(err)).Inc()
+	}
+	metrics.UpstreamRequestDuration.WithLabelValues(path, outcome).Observe(time.Since(start).Seconds())
+	return body, err
+}
+
+// failureReason classifies err for the upstream error metric.
+func failureReason(err error) string {
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr):
+		return fmt.Sprintf("status_%dxx", statusErr.StatusCode/100)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+	return "transport"
 }
 
-func (c *APIClient) doRequest(req *http.Request, result interface{}) error {
+// isProviderFailure reports whether err means the provider is unhealthy, as
+// opposed to a rejected request or a caller that gave up.
+func isProviderFailure(err error) bool {
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode >= http.StatusInternalServerError
+	case errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
+
+func (c *APIClient) doRequest(req *http.Request) ([]byte, error) {
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("error sending request: %w", err)
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
+	if c.Quota != nil {
+		c.Quota.Record(resp.Header)
+	}
+	trace.SpanFromContext(req.Context()).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("API returned non-success status: %d %s, Body: %s", resp.StatusCode, resp.Status, string(bodyBytes))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: string(bodyBytes)}
 	}
 
 	if resp.StatusCode == http.StatusNoContent {
-		return nil
+		return nil, nil
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading response body: %w", err)
+		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
+	return body, nil
+}
 
+func decodeResponse(body []byte, result interface{}) error {
 	if result != nil && len(body) > 0 {
 		if err := json.Unmarshal(body, result); err != nil {
 			return fmt.Errorf("error unmarshaling JSON response: %w (body: %s)", err, string(body))
 		}
 	}
-
 	return nil
 }
@@ -1,13 +1,26 @@
 package movieExternalApi
 
 import (
+	"context"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 func TestNewAPIClient(t *testing.T) {
 	baseURL := "http://testapi.com"
-	clientInterface := NewAPIClient(baseURL)
+	clientInterface := NewAPIClient(baseURL, nil, nil, nil, nil)
 
 	client, ok := clientInterface.(*APIClient)
 	if !ok {
@@ -24,3 +37,104 @@ func TestNewAPIClient(t *testing.T) {
 		t.Errorf("Expected HTTPClient timeout of 10s, got %v", client.HTTPClient.Timeout)
 	}
 }
+
+func TestAPIClient_GetCancelledWithContext(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	release := make(chan struct{})
+	defer close(release)
+	upstreamCancelled := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(upstreamCancelled)
+		case <-release:
+		}
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, nil, nil, nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var result ListMoviesResponse
+	err := client.Get(ctx, "/list_movies.json", nil, &result)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	select {
+	case <-upstreamCancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the provider request to be cancelled at the caller's deadline")
+	}
+}
+
+func TestAPIClient_GetPropagatesRequestContext(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+
+	var traceparent, requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		requestID = r.Header.Get("X-Request-ID")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	ctx, span := otel.Tracer("test").Start(logging.WithRequestID(context.Background(), "req-1"), "parent")
+	defer span.End()
+	if err := NewAPIClient(server.URL, nil, nil, nil, nil).Get(ctx, "/list_movies.json", nil, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(traceparent, span.SpanContext().TraceID().String()) {
+		t.Errorf("Expected the provider to receive the trace ID, got traceparent %q", traceparent)
+	}
+	if requestID != "req-1" {
+		t.Errorf("Expected the provider to receive the request ID, got %q", requestID)
+	}
+}
+
+func TestAPIClient_GetSharesIdenticalCalls(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Write([]byte(`{"status":"ok","data":{"movie_count":1}}`))
+	}))
+	defer server.Close()
+
+	client := NewAPIClient(server.URL, nil, nil, nil, nil)
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]ListMoviesResponse, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = client.Get(context.Background(), "/list_movies.json", map[string]string{"page": "1"}, &results[i])
+		}(i)
+	}
+	// Let every caller join the call in flight before it is answered.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected 1 call to the provider, got %d", got)
+	}
+	for i := range results {
+		if errs[i] != nil || results[i].Data.MovieCount != 1 {
+			t.Errorf("Expected caller %d to get the shared response, got %+v, %v", i, results[i], errs[i])
+		}
+	}
+
+	if err := client.Get(context.Background(), "/list_movies.json", map[string]string{"page": "2"}, nil); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("Expected different parameters to call the provider again, got %d calls", got)
+	}
+}
@@ -0,0 +1,212 @@
+package movieExternalApi
+
+import (
+	"errors"
+	"math"
+	"movierental/config"
+	"movierental/pkg/metrics"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// providerObservationTTL is how long the provider's reported limit is
+// trusted when it did not say when the limit resets.
+const providerObservationTTL = time.Hour
+
+var ErrQuotaExhausted = errors.New("movie provider budget is nearly spent; only cached responses are served")
+
+// Quota accounts for the calls made to the paid movie provider against the
+// configured daily and monthly budgets, and keeps the provider's own view of
+// its limit from the rate limit headers of its responses. Once either says
+// the budget is nearly spent, the client switches to serving cached
+// responses only.
+//
+// Counts are kept in memory, so they start from zero when the process
+// restarts and each instance counts its own calls; the provider's headers,
+// when it sends them, cover what the counts miss.
+type Quota struct {
+	mu               sync.Mutex
+	dailyBudget      int
+	monthlyBudget    int
+	cacheOnlyPercent int
+	day              time.Time
+	month            time.Time
+	dailyUsed        int
+	monthlyUsed      int
+	provider         *ProviderQuota
+	now              func() time.Time
+}
+
+// QuotaStatus is a snapshot of the budget, as shown to administrators.
+type QuotaStatus struct {
+	CacheOnly bool           `json:"cache_only"`
+	Daily     QuotaWindow    `json:"daily"`
+	Monthly   QuotaWindow    `json:"monthly"`
+	Provider  *ProviderQuota `json:"provider,omitempty"`
+}
+
+// QuotaWindow is the use of one budget window. Budget is zero and Remaining
+// nil when the window has no budget.
+type QuotaWindow struct {
+	Budget    int       `json:"budget"`
+	Used      int       `json:"used"`
+	Remaining *int      `json:"remaining,omitempty"`
+	ResetsAt  time.Time `json:"resets_at"`
+}
+
+// ProviderQuota is the limit the provider last reported.
+type ProviderQuota struct {
+	Limit      int        `json:"limit"`
+	Remaining  int        `json:"remaining"`
+	ResetsAt   *time.Time `json:"resets_at,omitempty"`
+	ObservedAt time.Time  `json:"observed_at"`
+}
+
+func NewQuota(cfg config.MovieQuotaConfig) *Quota {
+	if cfg.CacheOnlyPercent <= 0 || cfg.CacheOnlyPercent > 100 {
+		cfg.CacheOnlyPercent = 95
+	}
+	return &Quota{
+		dailyBudget:      cfg.DailyRequests,
+		monthlyBudget:    cfg.MonthlyRequests,
+		cacheOnlyPercent: cfg.CacheOnlyPercent,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call to the provider may be made, returning
+// ErrQuotaExhausted in cache-only mode.
+func (q *Quota) Allow() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.advance(q.now())
+	cacheOnly := q.cacheOnly()
+	q.updateMetrics(cacheOnly)
+	if cacheOnly {
+		return ErrQuotaExhausted
+	}
+	return nil
+}
+
+// Record counts a call that reached the provider and takes note of the rate
+// limit headers of its response. Calls are counted whatever their status,
+// because the provider bills them all.
+func (q *Quota) Record(header http.Header) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.advance(now)
+	q.dailyUsed++
+	q.monthlyUsed++
+	if provider, ok := parseProviderQuota(header, now); ok {
+		q.provider = provider
+	}
+	q.updateMetrics(q.cacheOnly())
+}
+
+// Status returns the current use of every budget.
+func (q *Quota) Status() QuotaStatus {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.advance(q.now())
+	status := QuotaStatus{
+		CacheOnly: q.cacheOnly(),
+		Daily:     window(q.dailyBudget, q.dailyUsed, q.day.AddDate(0, 0, 1)),
+		Monthly:   window(q.monthlyBudget, q.monthlyUsed, q.month.AddDate(0, 1, 0)),
+	}
+	if q.provider != nil {
+		provider := *q.provider
+		status.Provider = &provider
+	}
+	return status
+}
+
+func window(budget int, used int, resetsAt time.Time) QuotaWindow {
+	w := QuotaWindow{Budget: budget, Used: used, ResetsAt: resetsAt}
+	if budget > 0 {
+		remaining := max(budget-used, 0)
+		w.Remaining = &remaining
+	}
+	return w
+}
+
+// advance starts new windows once the UTC day or month has changed and
+// forgets the provider's limit once it has reset.
+func (q *Quota) advance(now time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if !day.Equal(q.day) {
+		q.day, q.dailyUsed = day, 0
+	}
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if !month.Equal(q.month) {
+		q.month, q.monthlyUsed = month, 0
+	}
+
+	if q.provider != nil {
+		expiresAt := q.provider.ObservedAt.Add(providerObservationTTL)
+		if q.provider.ResetsAt != nil {
+			expiresAt = *q.provider.ResetsAt
+		}
+		if !now.Before(expiresAt) {
+			q.provider = nil
+		}
+	}
+}
+
+func (q *Quota) cacheOnly() bool {
+	return q.spent(q.dailyBudget, q.dailyUsed) ||
+		q.spent(q.monthlyBudget, q.monthlyUsed) ||
+		(q.provider != nil && q.spent(q.provider.Limit, q.provider.Limit-q.provider.Remaining))
+}
+
+// spent reports whether used has reached the cache-only share of budget.
+func (q *Quota) spent(budget int, used int) bool {
+	if budget <= 0 {
+		return false
+	}
+	threshold := int(math.Ceil(float64(budget) * float64(q.cacheOnlyPercent) / 100))
+	return used >= threshold
+}
+
+func (q *Quota) updateMetrics(cacheOnly bool) {
+	if q.dailyBudget > 0 {
+		metrics.UpstreamQuotaRemaining.WithLabelValues("daily").Set(float64(max(q.dailyBudget-q.dailyUsed, 0)))
+	}
+	if q.monthlyBudget > 0 {
+		metrics.UpstreamQuotaRemaining.WithLabelValues("monthly").Set(float64(max(q.monthlyBudget-q.monthlyUsed, 0)))
+	}
+	if q.provider != nil {
+		metrics.UpstreamQuotaRemaining.WithLabelValues("provider").Set(float64(q.provider.Remaining))
+	}
+	if cacheOnly {
+		metrics.UpstreamCacheOnly.Set(1)
+	} else {
+		metrics.UpstreamCacheOnly.Set(0)
+	}
+}
+
+// parseProviderQuota reads RapidAPI's X-RateLimit-Requests-* headers, or
+// the plain X-RateLimit-* ones other gateways send. Reset is the number of
+// seconds until the limit resets.
+func parseProviderQuota(header http.Header, now time.Time) (*ProviderQuota, bool) {
+	for _, prefix := range []string{"X-RateLimit-Requests-", "X-RateLimit-"} {
+		limit, limitErr := strconv.Atoi(header.Get(prefix + "Limit"))
+		remaining, remainingErr := strconv.Atoi(header.Get(prefix + "Remaining"))
+		if limitErr != nil || remainingErr != nil || limit <= 0 {
+			continue
+		}
+		provider := &ProviderQuota{Limit: limit, Remaining: max(remaining, 0), ObservedAt: now}
+		if seconds, err := strconv.Atoi(header.Get(prefix + "Reset")); err == nil && seconds >= 0 {
+			resetsAt := now.Add(time.Duration(seconds) * time.Second)
+			provider.ResetsAt = &resetsAt
+		}
+		return provider, true
+	}
+	return nil, false
+}
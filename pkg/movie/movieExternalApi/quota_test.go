@@ -0,0 +1,103 @@
+package movieExternalApi
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuota_Budget(t *testing.T) {
+	now := time.Date(2026, 10, 31, 23, 0, 0, 0, time.UTC)
+	quota := NewQuota(config.MovieQuotaConfig{DailyRequests: 10, MonthlyRequests: 100, CacheOnlyPercent: 80})
+	quota.now = func() time.Time { return now }
+
+	for i := 0; i < 8; i++ {
+		if err := quota.Allow(); err != nil {
+			t.Fatalf("Expected call %d to be allowed, got: %v", i+1, err)
+		}
+		quota.Record(http.Header{})
+	}
+	if err := quota.Allow(); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("Expected cache-only mode at 80%% of the daily budget, got: %v", err)
+	}
+	status := quota.Status()
+	if !status.CacheOnly || status.Daily.Used != 8 || *status.Daily.Remaining != 2 || status.Monthly.Used != 8 {
+		t.Errorf("Unexpected status %+v", status)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if err := quota.Allow(); err != nil {
+		t.Errorf("Expected a new day and month to reset the budget, got: %v", err)
+	}
+	if status := quota.Status(); status.Daily.Used != 0 || status.Monthly.Used != 0 {
+		t.Errorf("Expected fresh windows, got %+v", status)
+	}
+}
+
+func TestQuota_ProviderHeaders(t *testing.T) {
+	now := time.Now()
+	quota := NewQuota(config.MovieQuotaConfig{CacheOnlyPercent: 90})
+	quota.now = func() time.Time { return now }
+
+	header := http.Header{}
+	header.Set("X-RateLimit-Requests-Limit", "500")
+	header.Set("X-RateLimit-Requests-Remaining", "100")
+	header.Set("X-RateLimit-Requests-Reset", "3600")
+	quota.Record(header)
+	if err := quota.Allow(); err != nil {
+		t.Fatalf("Expected calls while the provider has 20%% left, got: %v", err)
+	}
+
+	header.Set("X-RateLimit-Requests-Remaining", "50")
+	quota.Record(header)
+	if err := quota.Allow(); !errors.Is(err, ErrQuotaExhausted) {
+		t.Fatalf("Expected cache-only mode once the provider has 10%% left, got: %v", err)
+	}
+	if provider := quota.Status().Provider; provider == nil || provider.Remaining != 50 {
+		t.Errorf("Expected the provider's limit to be reported, got %+v", provider)
+	}
+
+	now = now.Add(time.Hour)
+	if err := quota.Allow(); err != nil {
+		t.Errorf("Expected calls again once the provider's limit reset, got: %v", err)
+	}
+}
+
+func TestAPIClient_CacheOnlyMode(t *testing.T) {
+	config.AppConfig = &config.Config{}
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	cache := NewResponseCache(config.MovieCacheConfig{TTLSeconds: 60})
+	cache.now = func() time.Time { return now }
+	quota := NewQuota(config.MovieQuotaConfig{DailyRequests: 1, CacheOnlyPercent: 100})
+	client := NewAPIClient(server.URL, nil, nil, cache, quota)
+	ctx := context.Background()
+
+	var result map[string]string
+	if err := client.Get(ctx, "/movie_details.json", map[string]string{"movie_id": "1"}, &result); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	result = nil
+	if err := client.Get(ctx, "/movie_details.json", map[string]string{"movie_id": "1"}, &result); err != nil || result["status"] != "ok" {
+		t.Fatalf("Expected the expired response to be served, got %v (%v)", result, err)
+	}
+	if err := client.Get(ctx, "/movie_details.json", map[string]string{"movie_id": "2"}, &result); !errors.Is(err, ErrQuotaExhausted) {
+		t.Errorf("Expected ErrQuotaExhausted for an uncached request, got: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected only the first call to reach the provider, got %d", calls.Load())
+	}
+}
@@ -0,0 +1,41 @@
+package movieExternalApi
+
+import (
+	"movierental/config"
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient returns an HTTP client for the movie provider tuned by cfg.
+// Its transport keeps connections alive between calls, so requests skip the
+// TCP and TLS handshakes, and caps how many connections the provider sees.
+// Other zero fields take the defaults of config.Defaults; a zero
+// MaxConnsPerHost leaves connections uncapped.
+func NewHTTPClient(cfg config.MovieTransportConfig) *http.Client {
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 32
+	}
+	if cfg.IdleConnTimeoutSeconds <= 0 {
+		cfg.IdleConnTimeoutSeconds = 90
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = 5 * time.Second
+	transport.MaxIdleConns = cfg.MaxIdleConnsPerHost
+	transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	transport.IdleConnTimeout = time.Duration(cfg.IdleConnTimeoutSeconds) * time.Second
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+}
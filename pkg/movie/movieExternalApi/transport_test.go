@@ -0,0 +1,49 @@
+package movieExternalApi
+
+import (
+	"movierental/config"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	client := NewHTTPClient(config.MovieTransportConfig{
+		TimeoutSeconds:         3,
+		MaxIdleConnsPerHost:    16,
+		MaxConnsPerHost:        24,
+		IdleConnTimeoutSeconds: 45,
+	})
+
+	if client.Timeout != 3*time.Second {
+		t.Errorf("Expected a timeout of 3s, got %v", client.Timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", client.Transport)
+	}
+	if transport.DisableKeepAlives {
+		t.Error("Expected keep-alives to be enabled")
+	}
+	if transport.MaxIdleConnsPerHost != 16 || transport.MaxConnsPerHost != 24 {
+		t.Errorf("Expected 16 idle and 24 connections per host, got %d and %d", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != 45*time.Second {
+		t.Errorf("Expected an idle timeout of 45s, got %v", transport.IdleConnTimeout)
+	}
+	if transport == http.DefaultTransport {
+		t.Error("Expected a transport of its own, not http.DefaultTransport")
+	}
+}
+
+func TestNewHTTPClient_Defaults(t *testing.T) {
+	client := NewHTTPClient(config.MovieTransportConfig{})
+
+	if client.Timeout != 10*time.Second {
+		t.Errorf("Expected a timeout of 10s, got %v", client.Timeout)
+	}
+	transport := client.Transport.(*http.Transport)
+	if transport.MaxIdleConnsPerHost != 32 || transport.MaxConnsPerHost != 0 {
+		t.Errorf("Expected 32 idle connections per host and no connection limit, got %d and %d", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+}
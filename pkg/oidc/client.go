@@ -0,0 +1,327 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"movierental/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+const (
+	// fetchTimeout bounds a discovery or key set fetch. Fetches are shared by
+	// every login waiting on them, so they do not end with the request that
+	// started them.
+	fetchTimeout = 10 * time.Second
+	// keyRefreshInterval is how soon after fetching the key set an unknown
+	// kid may trigger another fetch, so forged kids cannot force one per
+	// request.
+	keyRefreshInterval = time.Minute
+)
+
+// Provider implements the client side of the OpenID Connect authorization
+// code flow with PKCE for a single identity provider. Discovery and signing
+// keys are fetched lazily so an unreachable provider does not block startup.
+type Provider struct {
+	Name       string
+	Config     config.OIDCProviderConfig
+	HTTPClient *http.Client
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	keys          map[string]*rsa.PublicKey
+	keysFetchedAt time.Time
+	inflight      singleflight.Group
+}
+
+func NewProvider(name string, cfg config.OIDCProviderConfig) *Provider {
+	return &Provider{
+		Name:   name,
+		Config: cfg,
+		HTTPClient: &http.Client{
+			Timeout: fetchTimeout,
+		},
+	}
+}
+
+// CodeChallengeS256 derives the PKCE code challenge for verifier.
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *Provider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.Config.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+	params := authURL.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.Config.ClientID)
+	params.Set("redirect_uri", p.Config.RedirectURL)
+	params.Set("scope", strings.Join(scopes, " "))
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", codeChallenge)
+	params.Set("code_challenge_method", "S256")
+	authURL.RawQuery = params.Encode()
+	return authURL.String(), nil
+}
+
+func (p *Provider) Exchange(ctx context.Context, code string, codeVerifier string) (*TokenResponse, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL)
+	form.Set("client_id", p.Config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.Config.ClientSecret != "" {
+		form.Set("client_secret", p.Config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokenResponse TokenResponse
+	if err := p.doJSON(req, &tokenResponse); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+	return &tokenResponse, nil
+}
+
+// VerifyIDToken checks the ID token signature against the provider's JWKS and
+// validates issuer, audience, expiry and nonce.
+func (p *Provider) VerifyIDToken(ctx context.Context, rawIDToken string, nonce string) (*IDTokenClaims, error) {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(rawIDToken, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.Config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid id_token claims")
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("id_token is missing the subject claim")
+	}
+	idClaims := &IDTokenClaims{Subject: subject}
+	idClaims.Email, _ = claims["email"].(string)
+	idClaims.EmailVerified, _ = claims["email_verified"].(bool)
+	idClaims.Name, _ = claims["name"].(string)
+	idClaims.PreferredUsername, _ = claims["preferred_username"].(string)
+	return idClaims, nil
+}
+
+// Discovery returns the provider metadata, fetching it on first use. The
+// lock only guards the cached value; concurrent first calls share one fetch
+// so a slow provider does not serialise every login behind it.
+func (p *Provider) Discovery(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	p.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	result, err := p.shared(ctx, "discovery", func(ctx context.Context) (interface{}, error) {
+		discovery, err := p.fetchDiscovery(ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.mu.Lock()
+		p.discovery = discovery
+		p.mu.Unlock()
+		return discovery, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Discovery), nil
+}
+
+// shared runs fetch once for all concurrent callers with the same key. The
+// fetch keeps ctx's values but not its cancellation, and is bounded by
+// fetchTimeout instead; each caller still stops waiting when its own ctx
+// ends.
+func (p *Provider) shared(ctx context.Context, key string, fetch func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	results := p.inflight.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+		return fetch(fetchCtx)
+	})
+	select {
+	case result := <-results:
+		return result.Val, result.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *Provider) fetchDiscovery(ctx context.Context) (*Discovery, error) {
+	wellKnown := strings.TrimSuffix(p.Config.IssuerURL, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating discovery request: %w", err)
+	}
+	var discovery Discovery
+	if err := p.doJSON(req, &discovery); err != nil {
+		return nil, fmt.Errorf("OIDC discovery for provider '%s' failed: %w", p.Name, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.Config.IssuerURL, "/") {
+		return nil, fmt.Errorf("OIDC discovery issuer mismatch: expected %s, got %s", p.Config.IssuerURL, discovery.Issuer)
+	}
+	return &discovery, nil
+}
+
+// signingKey returns the RSA key for kid, refetching the key set when the
+// kid is unknown to pick up provider key rotation, at most once every
+// keyRefreshInterval.
+func (p *Provider) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	recentlyFetched := !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < keyRefreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if recentlyFetched {
+		return nil, fmt.Errorf("unknown signing key '%s'", kid)
+	}
+
+	if _, err := p.shared(ctx, "jwks", func(ctx context.Context) (interface{}, error) {
+		return nil, p.refreshKeys(ctx)
+	}); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key '%s'", kid)
+}
+
+func (p *Provider) refreshKeys(ctx context.Context) error {
+	discovery, err := p.Discovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discovery.JWKSURI, nil)
+	if err != nil {
+		return fmt.Errorf("error creating JWKS request: %w", err)
+	}
+	var keySet JSONWebKeySet
+	if err := p.doJSON(req, &keySet); err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range keySet.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(jwk)
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key '%s': %w", jwk.KeyID, err)
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	p.mu.Unlock()
+	return nil
+}
+
+func parseRSAKey(jwk JSONWebKey) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	exponent, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func (p *Provider) doJSON(req *http.Request, result interface{}) error {
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("provider returned non-success status: %d %s, Body: %s", resp.StatusCode, resp.Status, string(body))
+	}
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("error unmarshaling JSON response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/oidc/oidctest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// authorize follows the mock provider's authorization endpoint and returns
+// the code and state it redirects back with.
+func authorize(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Authorization request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from authorization endpoint, got %d", resp.StatusCode)
+	}
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect location: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	server := oidctest.NewServer("movierental")
+	defer server.Close()
+	server.SetUser(oidctest.User{Subject: "subject-1", Email: "oidc@example.com", EmailVerified: true, Name: "OIDC User"})
+
+	provider := NewProvider("mock", config.OIDCProviderConfig{
+		IssuerURL:   server.URL,
+		ClientID:    "movierental",
+		RedirectURL: "http://localhost:8080/auth/oidc/mock/callback",
+	})
+
+	verifier := "a-sufficiently-long-code-verifier-for-the-test-case"
+	authURL, err := provider.AuthCodeURL(context.Background(), "state-1", "nonce-1", CodeChallengeS256(verifier))
+	if err != nil {
+		t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	if !strings.Contains(authURL, "code_challenge_method=S256") {
+		t.Errorf("Expected PKCE parameters in the authorization URL, got %s", authURL)
+	}
+
+	t.Run("wrong code verifier is rejected", func(t *testing.T) {
+		code, _ := authorize(t, authURL)
+		if _, err := provider.Exchange(context.Background(), code, "not-the-verifier"); err == nil {
+			t.Error("Expected token exchange to fail with the wrong code verifier")
+		}
+	})
+
+	t.Run("successful exchange and verification", func(t *testing.T) {
+		code, state := authorize(t, authURL)
+		if state != "state-1" {
+			t.Errorf("Expected state 'state-1', got %q", state)
+		}
+
+		tokens, err := provider.Exchange(context.Background(), code, verifier)
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		claims, err := provider.VerifyIDToken(context.Background(), tokens.IDToken, "nonce-1")
+		if err != nil {
+			t.Fatalf("VerifyIDToken failed: %v", err)
+		}
+		if claims.Subject != "subject-1" || claims.Email != "oidc@example.com" || !claims.EmailVerified {
+			t.Errorf("Unexpected claims: %+v", claims)
+		}
+
+		if _, err := provider.VerifyIDToken(context.Background(), tokens.IDToken, "other-nonce"); err == nil {
+			t.Error("Expected a nonce mismatch to be rejected")
+		}
+	})
+
+	t.Run("token for another client is rejected", func(t *testing.T) {
+		other := NewProvider("mock", config.OIDCProviderConfig{
+			IssuerURL:   server.URL,
+			ClientID:    "someone-else",
+			RedirectURL: "http://localhost:8080/auth/oidc/mock/callback",
+		})
+		code, _ := authorize(t, authURL)
+		tokens, err := provider.Exchange(context.Background(), code, verifier)
+		if err != nil {
+			t.Fatalf("Exchange failed: %v", err)
+		}
+		if _, err := other.VerifyIDToken(context.Background(), tokens.IDToken, "nonce-1"); err == nil {
+			t.Error("Expected an audience mismatch to be rejected")
+		}
+	})
+}
+
+func TestProvider_DiscoveryFetchesWithoutHoldingLock(t *testing.T) {
+	var requests atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		json.NewEncoder(w).Encode(Discovery{Issuer: server.URL})
+	}))
+	defer server.Close()
+
+	provider := NewProvider("mock", config.OIDCProviderConfig{IssuerURL: server.URL, ClientID: "movierental"})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := provider.Discovery(context.Background())
+			errs <- err
+		}()
+	}
+
+	<-started
+	if !provider.mu.TryLock() {
+		t.Error("Expected the provider lock to be free while discovery is in flight")
+	} else {
+		provider.mu.Unlock()
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Discovery failed: %v", err)
+		}
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("Expected concurrent discovery calls to share one fetch, got %d requests", got)
+	}
+}
+
+func TestProvider_DiscoverySurvivesCancelledCaller(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		json.NewEncoder(w).Encode(Discovery{Issuer: server.URL})
+	}))
+	defer server.Close()
+
+	provider := NewProvider("mock", config.OIDCProviderConfig{IssuerURL: server.URL, ClientID: "movierental"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := provider.Discovery(ctx)
+		first <- err
+	}()
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		_, err := provider.Discovery(context.Background())
+		second <- err
+	}()
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the cancelled caller to stop waiting, got: %v", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("Expected the shared fetch to outlive the cancelled caller, got: %v", err)
+	}
+}
+
+func TestProvider_UnknownKeyRefetchesAtMostOncePerInterval(t *testing.T) {
+	var keySetRequests atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks" {
+			keySetRequests.Add(1)
+			json.NewEncoder(w).Encode(JSONWebKeySet{})
+			return
+		}
+		json.NewEncoder(w).Encode(Discovery{Issuer: server.URL, JWKSURI: server.URL + "/jwks"})
+	}))
+	defer server.Close()
+
+	provider := NewProvider("mock", config.OIDCProviderConfig{IssuerURL: server.URL, ClientID: "movierental"})
+
+	for i := 0; i < 3; i++ {
+		if _, err := provider.signingKey(context.Background(), "forged"); err == nil {
+			t.Fatal("Expected an unknown kid to be rejected")
+		}
+	}
+	if got := keySetRequests.Load(); got != 1 {
+		t.Errorf("Expected one key set fetch, got %d", got)
+	}
+
+	provider.mu.Lock()
+	provider.keysFetchedAt = time.Now().Add(-keyRefreshInterval)
+	provider.mu.Unlock()
+	provider.signingKey(context.Background(), "forged")
+	if got := keySetRequests.Load(); got != 2 {
+		t.Errorf("Expected the key set to be fetched again after the interval, got %d fetches", got)
+	}
+}
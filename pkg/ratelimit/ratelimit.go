@@ -0,0 +1,115 @@
+// Package ratelimit implements token bucket rate limiting. Buckets live in a
+// Store: MemoryStore keeps them in the process, which is enough for a single
+// instance; replicas sharing one limit need a Store backed by a shared
+// database such as Redis.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit refills a bucket at Rate tokens per second up to Burst tokens. Each
+// request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns the limit allowing requests per minute with the given
+// burst.
+func PerMinute(requests int, burst int) Limit {
+	return Limit{Rate: float64(requests) / 60, Burst: burst}
+}
+
+// Decision is the outcome of taking a token.
+type Decision struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// RetryAfter is how long until the next token is available; zero when
+	// the request was allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again.
+	ResetAfter time.Duration
+}
+
+// Store holds token buckets by key. Take must refill and take from the
+// bucket atomically, so concurrent requests cannot both spend the last
+// token.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+}
+
+// MemoryStore keeps buckets in memory. Buckets that have refilled completely
+// carry no state and are dropped periodically.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+	b.limit = limit
+	b.refill(now)
+
+	decision := Decision{}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = durationFor(1-b.tokens, limit.Rate)
+	}
+	decision.Remaining = int(math.Floor(b.tokens))
+	decision.ResetAfter = durationFor(float64(limit.Burst)-b.tokens, limit.Rate)
+	return decision, nil
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	}
+	b.updated = now
+}
+
+// durationFor returns how long refilling tokens takes at rate per second.
+func durationFor(tokens float64, rate float64) time.Duration {
+	if tokens <= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / rate * float64(time.Second))
+}
+
+func (s *MemoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	s.lastPrune = now
+	for key, b := range s.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
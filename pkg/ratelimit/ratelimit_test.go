@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	limit := PerMinute(60, 3)
+	now := time.Unix(1_800_000_000, 0)
+
+	for i := 2; i >= 0; i-- {
+		decision, _ := store.Take(ctx, "client", limit, now)
+		if !decision.Allowed || decision.Remaining != i {
+			t.Fatalf("Expected request to be allowed with %d remaining, got %+v", i, decision)
+		}
+	}
+
+	decision, _ := store.Take(ctx, "client", limit, now)
+	if decision.Allowed {
+		t.Fatal("Expected the burst to be exhausted")
+	}
+	if decision.RetryAfter != time.Second || decision.ResetAfter != 3*time.Second {
+		t.Errorf("Expected to retry after 1s and reset after 3s, got %+v", decision)
+	}
+
+	if decision, _ := store.Take(ctx, "other", limit, now); !decision.Allowed {
+		t.Error("Expected other keys to have their own bucket")
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	if decision, _ := store.Take(ctx, "client", limit, now); !decision.Allowed || decision.Remaining != 0 {
+		t.Errorf("Expected one token to have been refilled, got %+v", decision)
+	}
+
+	now = now.Add(time.Hour)
+	if decision, _ := store.Take(ctx, "client", limit, now); decision.Remaining != 2 {
+		t.Errorf("Expected the bucket to refill only up to its burst, got %+v", decision)
+	}
+	if len(store.buckets) != 1 {
+		t.Errorf("Expected full buckets to be pruned, got %d buckets", len(store.buckets))
+	}
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, apiKey *models.APIKey) error
+	ListByUser(ctx context.Context, userId string) ([]models.APIKey, error)
+	FindForUser(ctx context.Context, id string, userId string) (models.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	Revoke(ctx context.Context, id string, revokedAt time.Time) error
+	UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeyRepository) Create(ctx context.Context, apiKey *models.APIKey) error {
+	return translateError(r.db.WithContext(ctx).Create(apiKey).Error)
+}
+
+func (r *gormAPIKeyRepository) ListByUser(ctx context.Context, userId string) ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("created_at DESC").Find(&apiKeys).Error
+	return apiKeys, translateError(err)
+}
+
+func (r *gormAPIKeyRepository) FindForUser(ctx context.Context, id string, userId string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userId).First(&apiKey).Error
+	return apiKey, translateError(err)
+}
+
+func (r *gormAPIKeyRepository) FindByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	var apiKey models.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).First(&apiKey).Error
+	return apiKey, translateError(err)
+}
+
+func (r *gormAPIKeyRepository) Revoke(ctx context.Context, id string, revokedAt time.Time) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("revoked_at", &revokedAt).Error)
+}
+
+func (r *gormAPIKeyRepository) UpdateLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", &usedAt).Error)
+}
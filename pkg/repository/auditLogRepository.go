@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *models.AuditLog) error
+}
+
+type gormAuditLogRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditLogRepository) Create(ctx context.Context, entry *models.AuditLog) error {
+	return translateError(r.db.WithContext(ctx).Create(entry).Error)
+}
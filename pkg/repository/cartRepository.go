@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models/requests"
+
+	"gorm.io/gorm"
+)
+
+type CartRepository interface {
+	FindByUserID(ctx context.Context, userId string) (requests.Cart, error)
+	Create(ctx context.Context, cart *requests.Cart) error
+	Save(ctx context.Context, cart *requests.Cart) error
+	// List returns up to limit carts ordered by user, skipping the first
+	// offset.
+	List(ctx context.Context, limit int, offset int) ([]requests.Cart, error)
+}
+
+type gormCartRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormCartRepository) FindByUserID(ctx context.Context, userId string) (requests.Cart, error) {
+	var cart requests.Cart
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).First(&cart).Error
+	return cart, translateError(err)
+}
+
+func (r *gormCartRepository) Create(ctx context.Context, cart *requests.Cart) error {
+	return translateError(r.db.WithContext(ctx).Create(cart).Error)
+}
+
+func (r *gormCartRepository) Save(ctx context.Context, cart *requests.Cart) error {
+	return translateError(r.db.WithContext(ctx).Save(cart).Error)
+}
+
+func (r *gormCartRepository) List(ctx context.Context, limit int, offset int) ([]requests.Cart, error) {
+	var carts []requests.Cart
+	err := r.db.WithContext(ctx).Order("user_id").Limit(limit).Offset(offset).Find(&carts).Error
+	return carts, translateError(err)
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MFARecoveryCodeRepository interface {
+	// ReplaceForUser deletes the user's existing codes and stores codes.
+	ReplaceForUser(ctx context.Context, userId string, codes []models.MFARecoveryCode) error
+	// Consume marks an unused code as used, reporting whether one matched.
+	Consume(ctx context.Context, userId string, codeHash string, usedAt time.Time) (bool, error)
+}
+
+type gormMFARecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormMFARecoveryCodeRepository) ReplaceForUser(ctx context.Context, userId string, codes []models.MFARecoveryCode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return translateError(err)
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return translateError(tx.Create(&codes).Error)
+	})
+}
+
+func (r *gormMFARecoveryCodeRepository) Consume(ctx context.Context, userId string, codeHash string, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", &usedAt)
+	return result.RowsAffected > 0, translateError(result.Error)
+}
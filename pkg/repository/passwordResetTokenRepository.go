@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetTokenRepository interface {
+	Create(ctx context.Context, token *models.PasswordResetToken) error
+	// FindValid finds an unused token with tokenHash that has not expired at now.
+	FindValid(ctx context.Context, tokenHash string, now time.Time) (models.PasswordResetToken, error)
+	// MarkUsed marks the token used, reporting false if it already was.
+	MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error)
+}
+
+type gormPasswordResetTokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormPasswordResetTokenRepository) Create(ctx context.Context, token *models.PasswordResetToken) error {
+	return translateError(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *gormPasswordResetTokenRepository) FindValid(ctx context.Context, tokenHash string, now time.Time) (models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.WithContext(ctx).Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).First(&token).Error
+	return token, translateError(err)
+}
+
+func (r *gormPasswordResetTokenRepository) MarkUsed(ctx context.Context, id string, usedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", &usedAt)
+	return result.RowsAffected > 0, translateError(result.Error)
+}
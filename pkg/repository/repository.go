@@ -0,0 +1,73 @@
+// Package repository hides persistence behind small interfaces so services
+// receive their data access explicitly instead of using a global connection.
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// Store groups the repositories used by the services and runs units of work
+// across several of them.
+type Store struct {
+	Users               UserRepository
+	Carts               CartRepository
+	AuditLogs           AuditLogRepository
+	MFARecoveryCodes    MFARecoveryCodeRepository
+	PasswordResetTokens PasswordResetTokenRepository
+	UserIdentities      UserIdentityRepository
+	APIKeys             APIKeyRepository
+	Sessions            SessionRepository
+	UsedMFATokens       UsedMFATokenRepository
+
+	db *gorm.DB
+}
+
+// NewStore returns a Store whose repositories are backed by db.
+func NewStore(db *gorm.DB) *Store {
+	return &Store{
+		Users:               &gormUserRepository{db: db},
+		Carts:               &gormCartRepository{db: db},
+		AuditLogs:           &gormAuditLogRepository{db: db},
+		MFARecoveryCodes:    &gormMFARecoveryCodeRepository{db: db},
+		PasswordResetTokens: &gormPasswordResetTokenRepository{db: db},
+		UserIdentities:      &gormUserIdentityRepository{db: db},
+		APIKeys:             &gormAPIKeyRepository{db: db},
+		Sessions:            &gormSessionRepository{db: db},
+		UsedMFATokens:       &gormUsedMFATokenRepository{db: db},
+		db:                  db,
+	}
+}
+
+// Transaction runs fn with a Store whose repositories share one database
+// transaction, committing if fn returns nil. The transaction is rolled back
+// if ctx is cancelled first. A Store assembled by hand, for example from
+// fakes in tests, runs fn directly.
+func (s *Store) Transaction(ctx context.Context, fn func(tx *Store) error) error {
+	if s.db == nil {
+		return fn(s)
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewStore(tx))
+	})
+}
+
+// translateError maps GORM errors to the repository's sentinel errors.
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
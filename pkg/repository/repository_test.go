@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/utils"
+	"testing"
+)
+
+func TestStore_Transaction(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := NewStore(testDB)
+	ctx := context.Background()
+
+	t.Run("Commits when fn succeeds", func(t *testing.T) {
+		err := store.Transaction(ctx, func(tx *Store) error {
+			if err := tx.Users.Create(ctx, &models.User{ID: "user-commit", Username: "commituser", Email: "commit@example.com"}); err != nil {
+				return err
+			}
+			return tx.Carts.Create(ctx, &requests.Cart{Id: "cart-commit", UserId: "user-commit", Movies: []requests.CartMovieItem{}})
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := store.Carts.FindByUserID(ctx, "user-commit"); err != nil {
+			t.Errorf("Expected committed cart, got: %v", err)
+		}
+	})
+
+	t.Run("Rolls back when fn fails", func(t *testing.T) {
+		failure := errors.New("boom")
+		err := store.Transaction(ctx, func(tx *Store) error {
+			if err := tx.Users.Create(ctx, &models.User{ID: "user-rollback", Username: "rollbackuser", Email: "rollback@example.com"}); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("Expected fn's error, got: %v", err)
+		}
+		if _, err := store.Users.FindByID(ctx, "user-rollback"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after rollback, got: %v", err)
+		}
+	})
+}
+
+func TestSessionRepository_Rotate(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	sessions := NewStore(testDB).Sessions
+	ctx := context.Background()
+	session := models.Session{ID: "session-1", UserID: "user-1", RefreshTokenHash: "old"}
+	if err := sessions.Create(ctx, &session); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	rotated, err := sessions.Rotate(ctx, session.ID, "old", "new", "127.0.0.1", "go-test", session.CreatedAt)
+	if err != nil || !rotated {
+		t.Fatalf("Expected rotation to succeed, got rotated=%v err=%v", rotated, err)
+	}
+	// A second rotation from the same old hash loses the race.
+	rotated, err = sessions.Rotate(ctx, session.ID, "old", "newer", "127.0.0.1", "go-test", session.CreatedAt)
+	if err != nil || rotated {
+		t.Errorf("Expected stale rotation to be rejected, got rotated=%v err=%v", rotated, err)
+	}
+}
+
+func TestUserRepository_CreateDuplicate(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	users := NewStore(testDB).Users
+	ctx := context.Background()
+	if err := users.Create(ctx, &models.User{ID: "user-1", Username: "dupuser", Email: "dup@example.com"}); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	err := users.Create(ctx, &models.User{ID: "user-2", Username: "DupUser", Email: "other@example.com"})
+	if !errors.Is(err, ErrDuplicate) {
+		t.Errorf("Expected ErrDuplicate for a username differing only in case, got: %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	FindByID(ctx context.Context, id string) (models.Session, error)
+	// FindActiveByRefreshHash finds an unrevoked session with the given
+	// refresh token hash that has not expired at now.
+	FindActiveByRefreshHash(ctx context.Context, refreshTokenHash string, now time.Time) (models.Session, error)
+	ListActive(ctx context.Context, userId string, now time.Time) ([]models.Session, error)
+	// Rotate replaces the refresh token hash if it still equals oldHash and
+	// records the client, reporting whether it did.
+	Rotate(ctx context.Context, id string, oldHash string, newHash string, ipAddress string, userAgent string, seenAt time.Time) (bool, error)
+	UpdateLastSeen(ctx context.Context, id string, seenAt time.Time) error
+	// RevokeForUser revokes one active session of userId, reporting whether
+	// there was one.
+	RevokeForUser(ctx context.Context, id string, userId string, revokedAt time.Time) (bool, error)
+	RevokeAllForUser(ctx context.Context, userId string, revokedAt time.Time) error
+	// DeleteExpired removes sessions that expired before cutoff, returning
+	// how many were removed.
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return translateError(r.db.WithContext(ctx).Create(session).Error)
+}
+
+func (r *gormSessionRepository) FindByID(ctx context.Context, id string) (models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error
+	return session, translateError(err)
+}
+
+func (r *gormSessionRepository) FindActiveByRefreshHash(ctx context.Context, refreshTokenHash string, now time.Time) (models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).
+		Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", refreshTokenHash, now).
+		First(&session).Error
+	return session, translateError(err)
+}
+
+func (r *gormSessionRepository) ListActive(ctx context.Context, userId string, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, translateError(err)
+}
+
+func (r *gormSessionRepository) Rotate(ctx context.Context, id string, oldHash string, newHash string, ipAddress string, userAgent string, seenAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ?", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"last_seen_at":       seenAt,
+			"ip_address":         ipAddress,
+			"user_agent":         userAgent,
+		})
+	return result.RowsAffected > 0, translateError(result.Error)
+}
+
+func (r *gormSessionRepository) UpdateLastSeen(ctx context.Context, id string, seenAt time.Time) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).Update("last_seen_at", seenAt).Error)
+}
+
+func (r *gormSessionRepository) RevokeForUser(ctx context.Context, id string, userId string, revokedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userId).
+		Update("revoked_at", &revokedAt)
+	return result.RowsAffected > 0, translateError(result.Error)
+}
+
+func (r *gormSessionRepository) RevokeAllForUser(ctx context.Context, userId string, revokedAt time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", &revokedAt).Error
+	return translateError(err)
+}
+
+func (r *gormSessionRepository) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", cutoff).Delete(&models.Session{})
+	return result.RowsAffected, translateError(result.Error)
+}
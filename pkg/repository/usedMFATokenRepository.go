@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UsedMFATokenRepository interface {
+	// Claim records the token as used, reporting false if it already was.
+	Claim(ctx context.Context, token *models.UsedMFAToken) (bool, error)
+	IsUsed(ctx context.Context, id string) (bool, error)
+	// DeleteExpired removes tokens that expired before cutoff, returning how
+	// many were removed.
+	DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type gormUsedMFATokenRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUsedMFATokenRepository) Claim(ctx context.Context, token *models.UsedMFAToken) (bool, error) {
+	err := translateError(r.db.WithContext(ctx).Create(token).Error)
+	if errors.Is(err, ErrDuplicate) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *gormUsedMFATokenRepository) IsUsed(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.UsedMFAToken{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, translateError(err)
+}
+
+func (r *gormUsedMFATokenRepository) DeleteExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at < ?", cutoff).Delete(&models.UsedMFAToken{})
+	return result.RowsAffected, translateError(result.Error)
+}
@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository interface {
+	FindByProviderSubject(ctx context.Context, provider string, subject string) (models.UserIdentity, error)
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	UpdateLastLogin(ctx context.Context, id string, at time.Time) error
+}
+
+type gormUserIdentityRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserIdentityRepository) FindByProviderSubject(ctx context.Context, provider string, subject string) (models.UserIdentity, error) {
+	var identity models.UserIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return identity, translateError(err)
+}
+
+func (r *gormUserIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return translateError(r.db.WithContext(ctx).Create(identity).Error)
+}
+
+func (r *gormUserIdentityRepository) UpdateLastLogin(ctx context.Context, id string, at time.Time) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.UserIdentity{}).Where("id = ?", id).Update("last_login_at", at).Error)
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"movierental/pkg/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository interface {
+	FindByID(ctx context.Context, id string) (models.User, error)
+	// FindByLogin finds a user whose email or username equals identifier.
+	FindByLogin(ctx context.Context, identifier string) (models.User, error)
+	FindByEmail(ctx context.Context, email string) (models.User, error)
+	UsernameExists(ctx context.Context, username string) (bool, error)
+	Create(ctx context.Context, user *models.User) error
+	// Save writes every column of user.
+	Save(ctx context.Context, user *models.User) error
+	UpdatePassword(ctx context.Context, id string, passwordHash string) error
+	SetTOTPSecret(ctx context.Context, id string, secret string) error
+	EnableTOTP(ctx context.Context, id string, counter int64) error
+	// AdvanceTOTPCounter stores counter only if it is newer than the last
+	// used one, reporting whether it did.
+	AdvanceTOTPCounter(ctx context.Context, id string, counter int64) (bool, error)
+}
+
+type gormUserRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormUserRepository) FindByID(ctx context.Context, id string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	return user, translateError(err)
+}
+
+func (r *gormUserRepository) FindByLogin(ctx context.Context, identifier string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("email = ? OR username = ?", identifier, identifier).First(&user).Error
+	return user, translateError(err)
+}
+
+func (r *gormUserRepository) FindByEmail(ctx context.Context, email string) (models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error
+	return user, translateError(err)
+}
+
+func (r *gormUserRepository) UsernameExists(ctx context.Context, username string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Count(&count).Error
+	return count > 0, translateError(err)
+}
+
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return translateError(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *gormUserRepository) Save(ctx context.Context, user *models.User) error {
+	return translateError(r.db.WithContext(ctx).Save(user).Error)
+}
+
+func (r *gormUserRepository) UpdatePassword(ctx context.Context, id string, passwordHash string) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", passwordHash).Error)
+}
+
+func (r *gormUserRepository) SetTOTPSecret(ctx context.Context, id string, secret string) error {
+	return translateError(r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("totp_secret", secret).Error)
+}
+
+func (r *gormUserRepository) EnableTOTP(ctx context.Context, id string, counter int64) error {
+	err := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"totp_enabled":      true,
+		"totp_last_counter": counter,
+	}).Error
+	return translateError(err)
+}
+
+func (r *gormUserRepository) AdvanceTOTPCounter(ctx context.Context, id string, counter int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", id, counter).
+		Update("totp_last_counter", counter)
+	return result.RowsAffected > 0, translateError(result.Error)
+}
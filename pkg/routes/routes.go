@@ -1,48 +1,138 @@
 package routes
 
 import (
+	"context"
 	// _ "movierental/cmd/docs"
 	"movierental/config" // Import config to access AppConfig.MovieAPI.BaseURL
 	_ "movierental/docs"
 	"movierental/pkg/controller"
+	"movierental/pkg/database"
+	"movierental/pkg/health"
+	"movierental/pkg/metrics"
 	"movierental/pkg/middlewares"
+	"movierental/pkg/models"
 	"movierental/pkg/movie/movieExternalApi" // Import the movieExternalApi package
+	"movierental/pkg/ratelimit"
+	"movierental/pkg/repository"
 	"movierental/pkg/services"
+	"movierental/pkg/tracing"
+	"movierental/pkg/utils"
+	"movierental/pkg/worker"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRoutes(router *gin.Engine) {
+// SetupRoutes registers all routes on router. Services get their data access
+// through repositories backed by db, and their periodic jobs run on workers.
+func SetupRoutes(router *gin.Engine, db *gorm.DB, workers *worker.Group) {
+	router.Use(
+		middlewares.RequestID(),
+		middlewares.AccessLog(),
+		middlewares.Recover(),
+		tracing.Middleware(),
+		metrics.Middleware(),
+		middlewares.RequestTimeout(config.AppConfig.RequestTimeouts),
+	)
+
 	router.GET("/test", func(c *gin.Context) {
 		c.String(200, "Hello World!")
 	})
 
-	userService := &services.UserService{}
+	store := repository.NewStore(db)
+
+	sessionService := services.NewSessionService(store, config.AppConfig.Security.Sessions, config.AppConfig.Security.MFA)
+	workers.Every("purge-expired-sessions", time.Duration(config.AppConfig.Security.Sessions.CleanupIntervalMinutes)*time.Minute, sessionService.PurgeExpiredSessions)
+
+	loginGuard := services.NewLoginGuard(config.AppConfig.Security.Login)
+	userService := &services.UserService{
+		Store:            store,
+		LoginGuard:       loginGuard,
+		MFAPolicy:        config.AppConfig.Security.MFA,
+		PasswordResetTTL: time.Duration(config.AppConfig.Security.PasswordPolicy.ResetTokenTTLMinutes) * time.Minute,
+		Sessions:         sessionService,
+	}
+	mfaService := services.NewMFAService(store, config.AppConfig.Security.MFA.Issuer, sessionService, loginGuard)
+	workers.Every("purge-used-mfa-tokens", time.Duration(config.AppConfig.Security.Sessions.CleanupIntervalMinutes)*time.Minute, mfaService.PurgeUsedTokens)
+	oidcService := services.NewOIDCService(store, config.AppConfig.Security.OIDCProviders, config.AppConfig.Security.MFA, sessionService)
 
-	movieAPIClient := movieExternalApi.NewAPIClient(config.AppConfig.MovieAPI.BaseURL)
+	movieAPIBreaker := movieExternalApi.NewCircuitBreaker(config.AppConfig.MovieAPI.CircuitBreaker)
+	movieAPIQuota := movieExternalApi.NewQuota(config.AppConfig.MovieAPI.Quota)
+	movieAPIClient := movieExternalApi.NewAPIClient(config.AppConfig.MovieAPI.BaseURL,
+		movieExternalApi.NewHTTPClient(config.AppConfig.MovieAPI.Transport), movieAPIBreaker,
+		movieExternalApi.NewResponseCache(config.AppConfig.MovieAPI.Cache), movieAPIQuota)
 
 	movieService := services.NewMovieService(movieAPIClient)
 
-	cartService := &services.CartService{}
+	cartService := services.NewCartService(store.Carts)
+
+	apiKeyService := services.NewAPIKeyService(store)
+
+	rateLimiter := middlewares.NewRateLimiter(config.AppConfig.RateLimits, ratelimit.NewMemoryStore())
+
+	readiness := health.NewChecker()
+	readiness.Add("database", func(ctx context.Context) error { return database.Ping(ctx, db) })
+	readiness.Add("migrations", func(ctx context.Context) error { return database.CheckMigrations(ctx, db) })
+	readiness.AddInformational("movie_api", movieAPIBreaker.Check)
 
+	healthController := &controller.HealthController{Checker: readiness}
 	userController := &controller.UserController{UserService: userService}
 	movieController := &controller.MovieController{MovieService: movieService}
 	cartController := &controller.CartController{CartService: cartService}
+	mfaController := &controller.MFAController{MFAService: mfaService}
+	oidcController := &controller.OIDCController{OIDCService: oidcService}
+	apiKeyController := &controller.APIKeyController{APIKeyService: apiKeyService}
+	sessionController := &controller.SessionController{SessionService: sessionService}
+	movieAPIController := &controller.MovieAPIController{Quota: movieAPIQuota}
 
-	router.POST("/users", userController.CreateUser)
-	router.POST("/login", userController.LoginUser)
+	router.GET("/healthz", healthController.Liveness)
+	router.GET("/readyz", healthController.Readiness)
+
+	authLimit := rateLimiter.Limit("auth")
+	router.POST("/users", authLimit, userController.CreateUser)
+	router.POST("/login", authLimit, userController.LoginUser)
+	router.POST("/login/mfa", authLimit, mfaController.CompleteMFALogin)
+	router.POST("/password/reset", authLimit, userController.ResetPassword)
+	router.POST("/token/refresh", authLimit, sessionController.RefreshToken)
+	router.GET("/auth/oidc/:provider/login", authLimit, oidcController.StartOIDCLogin)
+	router.GET("/auth/oidc/:provider/callback", authLimit, oidcController.OIDCCallback)
 
 	authenticatedGroup := router.Group("/")
-	authenticatedGroup.Use(middlewares.Authenticate)
+	authenticatedGroup.Use(middlewares.Authenticate(apiKeyService, sessionService))
+	{
+		moviesLimit := rateLimiter.Limit("movies")
+		cartLimit := rateLimiter.Limit("cart")
+		authenticatedGroup.GET("/listallmovies", moviesLimit, middlewares.RequireScope(utils.ScopeMoviesRead), movieController.ListAllMovies)
+		authenticatedGroup.GET("/movie", moviesLimit, middlewares.RequireScope(utils.ScopeMoviesRead), movieController.MovieDetails)
+		authenticatedGroup.GET("/cart", cartLimit, middlewares.RequireScope(utils.ScopeCartRead), cartController.RetriveCart)
+		authenticatedGroup.POST("/cart", cartLimit, middlewares.RequireScope(utils.ScopeCartWrite), cartController.AddToCart)
+		authenticatedGroup.DELETE("/cart", cartLimit, middlewares.RequireScope(utils.ScopeCartWrite), cartController.RemoveFromCart)
+	}
+
+	// Credential management is only available with an interactive token.
+	accountGroup := router.Group("/me")
+	accountGroup.Use(middlewares.Authenticate(apiKeyService, sessionService), rateLimiter.Limit("account"), middlewares.RejectAPIKeys, middlewares.RequireScope(utils.ScopeAccountWrite))
+	{
+		accountGroup.POST("/mfa/totp", mfaController.EnrollTOTP)
+		accountGroup.POST("/mfa/totp/verify", mfaController.VerifyTOTPEnrollment)
+		accountGroup.PUT("/password", userController.ChangePassword)
+		accountGroup.POST("/tokens", userController.CreateScopedToken)
+		accountGroup.POST("/api-keys", apiKeyController.CreateAPIKey)
+		accountGroup.GET("/api-keys", apiKeyController.ListAPIKeys)
+		accountGroup.DELETE("/api-keys/:id", apiKeyController.RevokeAPIKey)
+		accountGroup.GET("/sessions", sessionController.ListSessions)
+		accountGroup.DELETE("/sessions/:id", sessionController.RevokeSession)
+	}
+
+	adminGroup := router.Group("/admin")
+	adminGroup.Use(middlewares.Authenticate(apiKeyService, sessionService), rateLimiter.Limit("admin"), middlewares.RequireRole(models.RoleAdmin))
 	{
-		authenticatedGroup.GET("/listallmovies", movieController.ListAllMovies)
-		authenticatedGroup.GET("/movie", movieController.MovieDetails)
-		authenticatedGroup.GET("/cart", cartController.RetriveCart)
-		authenticatedGroup.POST("/cart", cartController.AddToCart)
-		authenticatedGroup.DELETE("/cart", cartController.RemoveFromCart)
+		adminGroup.POST("/users/:id/password-reset", middlewares.RequireScope(utils.ScopeAdminUsers), userController.CreatePasswordReset)
+		adminGroup.GET("/movie-api/quota", middlewares.RequireScope(utils.ScopeAdminMovieAPI), movieAPIController.QuotaStatus)
 	}
 
 	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
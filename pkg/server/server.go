@@ -0,0 +1,89 @@
+// Package server runs the HTTP server and shuts it down gracefully.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movierental/config"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReadTimeout     = 15 * time.Second
+	defaultWriteTimeout    = 30 * time.Second
+	defaultIdleTimeout     = 60 * time.Second
+	defaultShutdownTimeout = 20 * time.Second
+)
+
+// New returns a server for handler listening on addr, with timeouts and
+// limits from cfg. Unset values fall back to defaults.
+func New(addr string, cfg config.ServerConfig, handler http.Handler) *http.Server {
+	maxHeaderBytes := cfg.MaxHeaderBytes
+	if maxHeaderBytes <= 0 {
+		maxHeaderBytes = http.DefaultMaxHeaderBytes
+	}
+	return &http.Server{
+		Addr:           addr,
+		Handler:        handler,
+		ReadTimeout:    secondsOr(cfg.ReadTimeoutSeconds, defaultReadTimeout),
+		WriteTimeout:   secondsOr(cfg.WriteTimeoutSeconds, defaultWriteTimeout),
+		IdleTimeout:    secondsOr(cfg.IdleTimeoutSeconds, defaultIdleTimeout),
+		MaxHeaderBytes: maxHeaderBytes,
+	}
+}
+
+// NewRouter returns an empty router that takes the client IP from
+// X-Forwarded-For or X-Real-IP only on connections from cfg.TrustedProxies.
+// Gin otherwise believes those headers from any peer, which would let
+// clients pick the IP that rate limits, login lockouts and the audit log see.
+func NewRouter(cfg config.ServerConfig) (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid server.trusted_proxies: %w", err)
+	}
+	return router, nil
+}
+
+// ShutdownTimeout returns how long in-flight requests may take to finish.
+func ShutdownTimeout(cfg config.ServerConfig) time.Duration {
+	return secondsOr(cfg.ShutdownTimeoutSeconds, defaultShutdownTimeout)
+}
+
+// Run serves until ctx is done, then stops accepting connections and waits
+// up to shutdownTimeout for in-flight requests to finish. It returns nil
+// after a clean shutdown.
+func Run(ctx context.Context, srv *http.Server, shutdownTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func secondsOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds <= 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"io"
+	"movierental/config"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew_Defaults(t *testing.T) {
+	srv := New(":0", config.ServerConfig{ReadTimeoutSeconds: 5}, http.NotFoundHandler())
+
+	if srv.ReadTimeout != 5*time.Second {
+		t.Errorf("Expected configured read timeout of 5s, got %v", srv.ReadTimeout)
+	}
+	if srv.WriteTimeout != defaultWriteTimeout {
+		t.Errorf("Expected default write timeout, got %v", srv.WriteTimeout)
+	}
+	if srv.MaxHeaderBytes != http.DefaultMaxHeaderBytes {
+		t.Errorf("Expected default max header bytes, got %d", srv.MaxHeaderBytes)
+	}
+}
+
+func TestRun_DrainsInFlightRequests(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve a port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	srv := New(addr, config.ServerConfig{}, handler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() { runErr <- Run(ctx, srv, time.Second) }()
+
+	responses := make(chan string, 1)
+	go func() {
+		var resp *http.Response
+		for i := 0; i < 50; i++ {
+			if resp, err = http.Get("http://" + addr); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	if body := <-responses; body != "done" {
+		t.Errorf("Expected the in-flight request to complete, got %q", body)
+	}
+	if err := <-runErr; err != nil {
+		t.Errorf("Expected a clean shutdown, got %v", err)
+	}
+}
+
+func TestNewRouter_TrustedProxies(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		proxies []string
+		want    string
+	}{
+		{"no proxies", nil, "203.0.113.7"},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "203.0.113.7"},
+		{"trusted peer", []string{"203.0.113.0/24"}, "198.51.100.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			router, err := NewRouter(config.ServerConfig{TrustedProxies: tc.proxies})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			var clientIP string
+			router.GET("/", func(c *gin.Context) { clientIP = c.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "203.0.113.7:4000"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			req.Header.Set("X-Real-IP", "198.51.100.1")
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if clientIP != tc.want {
+				t.Errorf("Expected client IP %s, got %s", tc.want, clientIP)
+			}
+		})
+	}
+
+	if _, err := NewRouter(config.ServerConfig{TrustedProxies: []string{"proxy"}}); err == nil {
+		t.Error("Expected an invalid proxy to be rejected")
+	}
+}
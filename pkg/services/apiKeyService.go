@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	apiKeyMarker = "mrk"
+	// apiKeyLastUsedResolution bounds how often last_used_at is written for
+	// a busy key.
+	apiKeyLastUsedResolution = time.Minute
+
+	AuditEventAPIKeyCreated = "api_key_created"
+	AuditEventAPIKeyRevoked = "api_key_revoked"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidAPIKey  = errors.New("invalid, expired or revoked API key")
+	ErrInvalidScope   = errors.New("invalid scope")
+)
+
+type APIKeyService struct {
+	Store *repository.Store
+}
+
+func NewAPIKeyService(store *repository.Store) *APIKeyService {
+	return &APIKeyService{Store: store}
+}
+
+// CreateAPIKey issues a new key for userId, limited to scopes the user's role
+// grants. The full key is returned only in this response; afterwards it is
+// identified by its prefix.
+func (aks *APIKeyService) CreateAPIKey(ctx context.Context, userId string, keyReq requests.CreateAPIKey) (map[string]interface{}, error) {
+	user, err := aks.Store.Users.FindByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user for API key: %w", err)
+	}
+	if err := utils.ValidateScopes(keyReq.Scopes, utils.DefaultScopesForRole(user.Role)); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScope, err)
+	}
+
+	prefixBytes, err := utils.GenerateRandomBytes(4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	prefix := apiKeyMarker + "_" + hex.EncodeToString(prefixBytes)
+	rawKey := prefix + "_" + secret
+
+	apiKey := models.APIKey{
+		ID:      uuid.New().String(),
+		UserID:  userId,
+		Name:    strings.TrimSpace(keyReq.Name),
+		Prefix:  prefix,
+		KeyHash: utils.HashToken(rawKey),
+		Scopes:  strings.Join(keyReq.Scopes, " "),
+	}
+	if keyReq.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, keyReq.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+	if err := aks.Store.APIKeys.Create(ctx, &apiKey); err != nil {
+		return nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+	recordAudit(ctx, aks.Store.AuditLogs, models.AuditLog{Event: AuditEventAPIKeyCreated, UserID: userId, Identifier: prefix})
+
+	response := apiKeyView(apiKey)
+	response["message"] = "API key created. Store it now; it will not be shown again."
+	response["key"] = rawKey
+	return response, nil
+}
+
+func (aks *APIKeyService) ListAPIKeys(ctx context.Context, userId string) (map[string]interface{}, error) {
+	apiKeys, err := aks.Store.APIKeys.ListByUser(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	views := make([]gin.H, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		views = append(views, apiKeyView(apiKey))
+	}
+	return gin.H{"api_keys": views}, nil
+}
+
+// RevokeAPIKey revokes one of userId's keys. Revoking an already revoked key
+// succeeds without changing its revocation time.
+func (aks *APIKeyService) RevokeAPIKey(ctx context.Context, userId string, keyId string) (map[string]interface{}, error) {
+	apiKey, err := aks.Store.APIKeys.FindForUser(ctx, keyId, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if apiKey.RevokedAt == nil {
+		if err := aks.Store.APIKeys.Revoke(ctx, apiKey.ID, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		recordAudit(ctx, aks.Store.AuditLogs, models.AuditLog{Event: AuditEventAPIKeyRevoked, UserID: userId, Identifier: apiKey.Prefix})
+	}
+	return gin.H{"message": "API key revoked", "id": apiKey.ID}, nil
+}
+
+// VerifyAPIKey resolves a raw key to the claims of its owner, as if the
+// owner had presented an access token.
+func (aks *APIKeyService) VerifyAPIKey(ctx context.Context, rawKey string) (utils.TokenClaims, error) {
+	if !strings.HasPrefix(rawKey, apiKeyMarker+"_") {
+		return utils.TokenClaims{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := aks.Store.APIKeys.FindByHash(ctx, utils.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return utils.TokenClaims{}, ErrInvalidAPIKey
+		}
+		return utils.TokenClaims{}, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	now := time.Now()
+	if apiKey.RevokedAt != nil || (apiKey.ExpiresAt != nil && !apiKey.ExpiresAt.After(now)) {
+		return utils.TokenClaims{}, ErrInvalidAPIKey
+	}
+
+	user, err := aks.Store.Users.FindByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return utils.TokenClaims{}, ErrInvalidAPIKey
+		}
+		return utils.TokenClaims{}, fmt.Errorf("failed to retrieve API key owner: %w", err)
+	}
+
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyLastUsedResolution {
+		if err := aks.Store.APIKeys.UpdateLastUsed(ctx, apiKey.ID, now); err != nil {
+			slog.WarnContext(ctx, "Failed to update last use of API key", "key_prefix", apiKey.Prefix, "error", err)
+		}
+	}
+
+	// A key never grants more than its owner's current role allows.
+	roleScopes := utils.DefaultScopesForRole(user.Role)
+	var scopes []string
+	for _, scope := range strings.Fields(apiKey.Scopes) {
+		if utils.HasScope(roleScopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return utils.TokenClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
+		Scopes: scopes,
+	}, nil
+}
+
+func apiKeyView(apiKey models.APIKey) gin.H {
+	return gin.H{
+		"id":           apiKey.ID,
+		"name":         apiKey.Name,
+		"prefix":       apiKey.Prefix,
+		"scopes":       strings.Fields(apiKey.Scopes),
+		"created_at":   apiKey.CreatedAt,
+		"expires_at":   apiKey.ExpiresAt,
+		"last_used_at": apiKey.LastUsedAt,
+		"revoked_at":   apiKey.RevokedAt,
+	}
+}
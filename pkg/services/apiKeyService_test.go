@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyService(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	apiKeyService := NewAPIKeyService(store)
+	testUserID := "user-api-key-1"
+	utils.CreateTestUserAndCart(testDB, testUserID, "apikey@example.com")
+
+	response, err := apiKeyService.CreateAPIKey(context.Background(), testUserID, requests.CreateAPIKey{Name: "partner", Scopes: []string{"movies:read"}, ExpiresInDays: 30})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	rawKey, _ := response["key"].(string)
+	prefix, _ := response["prefix"].(string)
+	keyID, _ := response["id"].(string)
+	if !strings.HasPrefix(rawKey, prefix+"_") {
+		t.Fatalf("Expected key %q to start with prefix %q", rawKey, prefix)
+	}
+
+	t.Run("Key is stored hashed", func(t *testing.T) {
+		var stored models.APIKey
+		testDB.Where("id = ?", keyID).First(&stored)
+		if stored.KeyHash == rawKey || stored.KeyHash != utils.HashToken(rawKey) {
+			t.Errorf("Expected only the key hash to be stored, got %q", stored.KeyHash)
+		}
+	})
+
+	t.Run("Valid key resolves to its owner", func(t *testing.T) {
+		claims, err := apiKeyService.VerifyAPIKey(context.Background(), rawKey)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if claims.UserID != testUserID {
+			t.Errorf("Expected user %s, got %s", testUserID, claims.UserID)
+		}
+		if len(claims.Scopes) != 1 || claims.Scopes[0] != utils.ScopeMoviesRead {
+			t.Errorf("Expected only the key's scopes, got %v", claims.Scopes)
+		}
+		var stored models.APIKey
+		testDB.Where("id = ?", keyID).First(&stored)
+		if stored.LastUsedAt == nil {
+			t.Error("Expected last_used_at to be recorded")
+		}
+	})
+
+	t.Run("Scopes beyond the owner's role are rejected", func(t *testing.T) {
+		_, err := apiKeyService.CreateAPIKey(context.Background(), testUserID, requests.CreateAPIKey{Name: "admin", Scopes: []string{utils.ScopeAdminAll}})
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("Expected ErrInvalidScope, got: %v", err)
+		}
+	})
+
+	t.Run("Unknown key is rejected", func(t *testing.T) {
+		if _, err := apiKeyService.VerifyAPIKey(context.Background(), prefix+"_not-the-secret"); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Expected ErrInvalidAPIKey, got: %v", err)
+		}
+	})
+
+	t.Run("Expired key is rejected", func(t *testing.T) {
+		expired, err := apiKeyService.CreateAPIKey(context.Background(), testUserID, requests.CreateAPIKey{Name: "old", Scopes: []string{"movies:read"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		testDB.Model(&models.APIKey{}).Where("id = ?", expired["id"]).Update("expires_at", time.Now().Add(-time.Minute))
+		if _, err := apiKeyService.VerifyAPIKey(context.Background(), expired["key"].(string)); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Expected ErrInvalidAPIKey, got: %v", err)
+		}
+	})
+
+	t.Run("Other users cannot revoke the key", func(t *testing.T) {
+		if _, err := apiKeyService.RevokeAPIKey(context.Background(), "someone-else", keyID); !errors.Is(err, ErrAPIKeyNotFound) {
+			t.Errorf("Expected ErrAPIKeyNotFound, got: %v", err)
+		}
+	})
+
+	t.Run("Revoked key is rejected", func(t *testing.T) {
+		if _, err := apiKeyService.RevokeAPIKey(context.Background(), testUserID, keyID); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := apiKeyService.VerifyAPIKey(context.Background(), rawKey); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("Expected ErrInvalidAPIKey, got: %v", err)
+		}
+	})
+
+	t.Run("List never includes secrets", func(t *testing.T) {
+		response, err := apiKeyService.ListAPIKeys(context.Background(), testUserID)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		keys, _ := response["api_keys"].([]gin.H)
+		if len(keys) != 2 {
+			t.Fatalf("Expected 2 keys, got %d", len(keys))
+		}
+		for _, key := range keys {
+			if _, ok := key["key"]; ok {
+				t.Error("Expected listed keys not to include the secret")
+			}
+		}
+	})
+}
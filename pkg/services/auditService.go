@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"movierental/pkg/metrics"
+	"movierental/pkg/models"
+	"movierental/pkg/repository"
+
+	"github.com/google/uuid"
+)
+
+const AuditEventLoginFailed = "login_failed"
+
+// recordAudit writes entry even if ctx has been cancelled, so a client that
+// disconnects cannot prevent its actions from being recorded.
+func recordAudit(ctx context.Context, auditLogs repository.AuditLogRepository, entry models.AuditLog) {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	if err := auditLogs.Create(context.WithoutCancel(ctx), &entry); err != nil {
+		slog.ErrorContext(ctx, "Failed to write audit entry", "event", entry.Event, "error", err)
+	}
+}
+
+func recordFailedLogin(ctx context.Context, auditLogs repository.AuditLogRepository, userID string, identifier string, clientIP string, reason string) {
+	metrics.LoginFailures.WithLabelValues(reason).Inc()
+	recordAudit(ctx, auditLogs, models.AuditLog{
+		Event:      AuditEventLoginFailed,
+		UserID:     userID,
+		Identifier: identifier,
+		IPAddress:  clientIP,
+		Detail:     reason,
+	})
+}
@@ -1,22 +1,28 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"movierental/pkg/database"
+	"movierental/pkg/metrics"
 	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
-type CartService struct{}
+type CartService struct {
+	Carts repository.CartRepository
+}
+
+func NewCartService(carts repository.CartRepository) *CartService {
+	return &CartService{Carts: carts}
+}
 
-func (cs *CartService) RetrieveCart(userId interface{}) (requests.Cart, error) {
-	var retrievedCart requests.Cart
-	err := database.DB.Where("user_id = ?", userId).First(&retrievedCart).Error
+func (cs *CartService) RetrieveCart(ctx context.Context, userId interface{}) (requests.Cart, error) {
+	retrievedCart, err := cs.Carts.FindByUserID(ctx, fmt.Sprint(userId))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, repository.ErrNotFound) {
 			return requests.Cart{}, errors.New("cart not found for user")
 		}
 		return requests.Cart{}, fmt.Errorf("failed to retrieve cart: %w", err)
@@ -24,11 +30,27 @@ func (cs *CartService) RetrieveCart(userId interface{}) (requests.Cart, error) {
 	return retrievedCart, nil
 }
 
-func (cs *CartService) AddToCart(userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error) {
-	var existingCart requests.Cart
-	err := database.DB.Where("user_id = ?", userId).First(&existingCart).Error
+// ListCarts returns a page of carts with the number of movies in each.
+func (cs *CartService) ListCarts(ctx context.Context, limit int, offset int) (map[string]interface{}, error) {
+	carts, err := cs.Carts.List(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list carts: %w", err)
+	}
+	views := make([]gin.H, 0, len(carts))
+	for _, cart := range carts {
+		views = append(views, gin.H{
+			"cart_id":     cart.Id,
+			"user_id":     cart.UserId,
+			"movie_count": len(cart.Movies),
+		})
+	}
+	return gin.H{"carts": views}, nil
+}
+
+func (cs *CartService) AddToCart(ctx context.Context, userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error) {
+	existingCart, err := cs.Carts.FindByUserID(ctx, fmt.Sprint(userId))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, fmt.Errorf("cart for user ID '%s' not found. Please ensure the user exists and their cart is created", userId)
 		}
 		return nil, fmt.Errorf("failed to retrieve cart for adding item: %w", err)
@@ -42,24 +64,23 @@ func (cs *CartService) AddToCart(userId interface{}, movieItem requests.CartMovi
 
 	existingCart.Movies = append(existingCart.Movies, movieItem)
 
-	saveResult := database.DB.Save(&existingCart)
-	if saveResult.Error != nil {
-		return nil, fmt.Errorf("failed to add movie to cart due to a database error: %w", saveResult.Error)
+	if err := cs.Carts.Save(ctx, &existingCart); err != nil {
+		return nil, fmt.Errorf("failed to add movie to cart due to a database error: %w", err)
 	}
+	metrics.CartAdds.Inc()
 
 	return gin.H{
 		"message":        fmt.Sprintf("Movie '%s' (ID: %d) added to cart successfully.", movieItem.Title, movieItem.ID),
 		"cart_id":        existingCart.Id,
 		"user_id":        existingCart.UserId,
-		"current_movies": existingCart.Movies,
+		"current_movies": []requests.CartMovieItem(existingCart.Movies),
 	}, nil
 }
 
-func (cs *CartService) RemoveFromCart(userId interface{}, movieID int) (map[string]interface{}, error) {
-	var existingCart requests.Cart
-	err := database.DB.Where("user_id = ?", userId).First(&existingCart).Error
+func (cs *CartService) RemoveFromCart(ctx context.Context, userId interface{}, movieID int) (map[string]interface{}, error) {
+	existingCart, err := cs.Carts.FindByUserID(ctx, fmt.Sprint(userId))
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
+		if errors.Is(err, repository.ErrNotFound) {
 			return nil, fmt.Errorf("cart for user ID '%s' not found", userId)
 		}
 		return nil, fmt.Errorf("failed to retrieve cart for removing item: %w", err)
@@ -79,15 +100,14 @@ func (cs *CartService) RemoveFromCart(userId interface{}, movieID int) (map[stri
 
 	existingCart.Movies = append(existingCart.Movies[:foundIndex], existingCart.Movies[foundIndex+1:]...)
 
-	saveResult := database.DB.Save(&existingCart)
-	if saveResult.Error != nil {
-		return nil, fmt.Errorf("failed to remove movie from cart due to a database error: %w", saveResult.Error)
+	if err := cs.Carts.Save(ctx, &existingCart); err != nil {
+		return nil, fmt.Errorf("failed to remove movie from cart due to a database error: %w", err)
 	}
 
 	return gin.H{
 		"message":        fmt.Sprintf("Movie with ID %d removed from cart successfully.", movieID),
 		"cart_id":        existingCart.Id,
 		"user_id":        existingCart.UserId,
-		"current_movies": existingCart.Movies,
+		"current_movies": []requests.CartMovieItem(existingCart.Movies),
 	}, nil
 }
@@ -1,8 +1,9 @@
 package services
 
 import (
-	"movierental/pkg/database"
+	"context"
 	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
 	"movierental/pkg/utils"
 	"testing"
 )
@@ -11,17 +12,15 @@ func TestCartService_RetrieveCart(t *testing.T) {
 	testDB := utils.SetupTestDB()
 	defer utils.ClearTestDB(testDB)
 
-	originalDB := database.DB
-	database.DB = testDB
-	defer func() { database.DB = originalDB }()
+	store := repository.NewStore(testDB)
 
-	cartService := &CartService{}
+	cartService := NewCartService(store.Carts)
 
 	testUserID := "user-cart-1"
 	utils.CreateTestUserAndCart(testDB, testUserID, "cart1@example.com")
 
 	t.Run("Successfully retrieve cart", func(t *testing.T) {
-		cart, err := cartService.RetrieveCart(testUserID)
+		cart, err := cartService.RetrieveCart(context.Background(), testUserID)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -34,7 +33,7 @@ func TestCartService_RetrieveCart(t *testing.T) {
 	})
 
 	t.Run("Cart not found for non-existent user", func(t *testing.T) {
-		_, err := cartService.RetrieveCart("nonexistent-user")
+		_, err := cartService.RetrieveCart(context.Background(), "nonexistent-user")
 		if err == nil {
 			t.Error("Expected an error for non-existent cart, got none")
 		}
@@ -49,11 +48,9 @@ func TestCartService_AddToCart(t *testing.T) {
 	testDB := utils.SetupTestDB()
 	defer utils.ClearTestDB(testDB)
 
-	originalDB := database.DB
-	database.DB = testDB
-	defer func() { database.DB = originalDB }()
+	store := repository.NewStore(testDB)
 
-	cartService := &CartService{}
+	cartService := NewCartService(store.Carts)
 
 	testUserID := "user-cart-2"
 	utils.CreateTestUserAndCart(testDB, testUserID, "cart2@example.com")
@@ -62,7 +59,7 @@ func TestCartService_AddToCart(t *testing.T) {
 	movieItem2 := requests.CartMovieItem{ID: 102, Title: "Movie B"}
 
 	t.Run("Add first movie successfully", func(t *testing.T) {
-		response, err := cartService.AddToCart(testUserID, movieItem1)
+		response, err := cartService.AddToCart(context.Background(), testUserID, movieItem1)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -85,7 +82,7 @@ func TestCartService_AddToCart(t *testing.T) {
 	})
 
 	t.Run("Add second movie successfully", func(t *testing.T) {
-		response, err := cartService.AddToCart(testUserID, movieItem2)
+		response, err := cartService.AddToCart(context.Background(), testUserID, movieItem2)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -108,7 +105,7 @@ func TestCartService_AddToCart(t *testing.T) {
 	})
 
 	t.Run("Add duplicate movie", func(t *testing.T) {
-		response, err := cartService.AddToCart(testUserID, movieItem1)
+		response, err := cartService.AddToCart(context.Background(), testUserID, movieItem1)
 		if err == nil {
 			t.Error("Expected an error for duplicate movie, got none")
 		}
@@ -124,7 +121,7 @@ func TestCartService_AddToCart(t *testing.T) {
 
 	t.Run("Cart not found (non-existent user)", func(t *testing.T) {
 		nonExistentUserID := "nonexistent-user-id"
-		response, err := cartService.AddToCart(nonExistentUserID, movieItem1)
+		response, err := cartService.AddToCart(context.Background(), nonExistentUserID, movieItem1)
 		if err == nil {
 			t.Error("Expected an error for non-existent cart, got none")
 		}
@@ -143,11 +140,9 @@ func TestCartService_RemoveFromCart(t *testing.T) {
 	testDB := utils.SetupTestDB()
 	defer utils.ClearTestDB(testDB)
 
-	originalDB := database.DB
-	database.DB = testDB
-	defer func() { database.DB = originalDB }()
+	store := repository.NewStore(testDB)
 
-	cartService := &CartService{}
+	cartService := NewCartService(store.Carts)
 
 	testUserID := "user-cart-3"
 	utils.CreateTestUserAndCart(testDB, testUserID, "cart3@example.com")
@@ -155,11 +150,11 @@ func TestCartService_RemoveFromCart(t *testing.T) {
 	movieItem1 := requests.CartMovieItem{ID: 201, Title: "Movie X"}
 	movieItem2 := requests.CartMovieItem{ID: 202, Title: "Movie Y"}
 
-	_, _ = cartService.AddToCart(testUserID, movieItem1)
-	_, _ = cartService.AddToCart(testUserID, movieItem2)
+	_, _ = cartService.AddToCart(context.Background(), testUserID, movieItem1)
+	_, _ = cartService.AddToCart(context.Background(), testUserID, movieItem2)
 
 	t.Run("Remove existing movie successfully", func(t *testing.T) {
-		response, err := cartService.RemoveFromCart(testUserID, movieItem1.ID)
+		response, err := cartService.RemoveFromCart(context.Background(), testUserID, movieItem1.ID)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -182,7 +177,7 @@ func TestCartService_RemoveFromCart(t *testing.T) {
 	})
 
 	t.Run("Try to remove non-existent movie", func(t *testing.T) {
-		response, err := cartService.RemoveFromCart(testUserID, 999)
+		response, err := cartService.RemoveFromCart(context.Background(), testUserID, 999)
 		if err == nil {
 			t.Error("Expected an error for non-existent movie in cart, got none")
 		}
@@ -196,7 +191,7 @@ func TestCartService_RemoveFromCart(t *testing.T) {
 	})
 
 	t.Run("Remove the last movie", func(t *testing.T) {
-		response, err := cartService.RemoveFromCart(testUserID, movieItem2.ID)
+		response, err := cartService.RemoveFromCart(context.Background(), testUserID, movieItem2.ID)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -217,7 +212,7 @@ func TestCartService_RemoveFromCart(t *testing.T) {
 
 	t.Run("Cart not found (non-existent user for removal)", func(t *testing.T) {
 		nonExistentUserID := "nonexistent-user-id-remove"
-		response, err := cartService.RemoveFromCart(nonExistentUserID, movieItem1.ID)
+		response, err := cartService.RemoveFromCart(context.Background(), nonExistentUserID, movieItem1.ID)
 		if err == nil {
 			t.Error("Expected an error for non-existent cart, got none")
 		}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"movierental/config"
+	"sync"
+	"time"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many failed login attempts. Please try again later"
+}
+
+type loginFailures struct {
+	count        int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// LoginGuard tracks failed logins per account and per client IP. Once a key
+// exceeds its free attempts every further failure doubles the wait before the
+// next attempt is allowed, and reaching the maximum locks the key out entirely.
+type LoginGuard struct {
+	mu        sync.Mutex
+	cfg       config.LoginProtectionConfig
+	failures  map[string]*loginFailures
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func NewLoginGuard(cfg config.LoginProtectionConfig) *LoginGuard {
+	if cfg.FreeAttempts <= 0 {
+		cfg.FreeAttempts = 3
+	}
+	if cfg.MaxAccountFailures <= 0 {
+		cfg.MaxAccountFailures = 10
+	}
+	if cfg.MaxIPFailures <= 0 {
+		cfg.MaxIPFailures = 50
+	}
+	if cfg.BaseDelaySeconds <= 0 {
+		cfg.BaseDelaySeconds = 1
+	}
+	if cfg.MaxDelaySeconds <= 0 {
+		cfg.MaxDelaySeconds = 60
+	}
+	if cfg.LockoutSeconds <= 0 {
+		cfg.LockoutSeconds = 900
+	}
+	if cfg.FailureWindowSeconds <= 0 {
+		cfg.FailureWindowSeconds = 900
+	}
+	return &LoginGuard{
+		cfg:      cfg,
+		failures: make(map[string]*loginFailures),
+		now:      time.Now,
+	}
+}
+
+func accountKey(account string) string { return "account:" + account }
+
+// userAccount names an existing account to the guard. Failures count against
+// the account whichever of its email or username was typed.
+func userAccount(userId string) string { return "user:" + userId }
+
+// unknownAccount names a login identifier that matches no account, kept apart
+// from user IDs so typing someone's ID cannot lock them out.
+func unknownAccount(identifier string) string { return "login:" + identifier }
+
+func ipKey(ip string) string { return "ip:" + ip }
+
+// Check reports how long the caller has to wait before another attempt for
+// this account or IP is allowed. A zero duration means the attempt may proceed.
+func (g *LoginGuard) Check(account string, ip string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range []string{accountKey(account), ipKey(ip)} {
+		entry := g.activeEntry(key, now)
+		if entry == nil {
+			continue
+		}
+		if remaining := entry.blockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+	return wait
+}
+
+func (g *LoginGuard) RecordFailure(account string, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.recordFailure(accountKey(account), g.cfg.MaxAccountFailures, now)
+	g.recordFailure(ipKey(ip), g.cfg.MaxIPFailures, now)
+	g.prune(now)
+}
+
+// RecordSuccess clears the account's failure history. The IP history is kept
+// so that a successful login on one account does not reset password spraying
+// detection across many accounts.
+func (g *LoginGuard) RecordSuccess(account string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.failures, accountKey(account))
+}
+
+func (g *LoginGuard) activeEntry(key string, now time.Time) *loginFailures {
+	entry, ok := g.failures[key]
+	if !ok {
+		return nil
+	}
+	window := time.Duration(g.cfg.FailureWindowSeconds) * time.Second
+	if now.After(entry.blockedUntil) && now.Sub(entry.lastFailure) > window {
+		delete(g.failures, key)
+		return nil
+	}
+	return entry
+}
+
+func (g *LoginGuard) recordFailure(key string, maxFailures int, now time.Time) {
+	entry := g.activeEntry(key, now)
+	if entry == nil {
+		entry = &loginFailures{}
+		g.failures[key] = entry
+	}
+	entry.count++
+	entry.lastFailure = now
+
+	switch {
+	case entry.count >= maxFailures:
+		entry.blockedUntil = now.Add(time.Duration(g.cfg.LockoutSeconds) * time.Second)
+	case entry.count > g.cfg.FreeAttempts:
+		delay := time.Duration(g.cfg.BaseDelaySeconds) * time.Second
+		maxDelay := time.Duration(g.cfg.MaxDelaySeconds) * time.Second
+		for i := g.cfg.FreeAttempts + 1; i < entry.count && delay < maxDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+		entry.blockedUntil = now.Add(delay)
+	}
+}
+
+func (g *LoginGuard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < time.Minute {
+		return
+	}
+	g.lastPrune = now
+	for key := range g.failures {
+		g.activeEntry(key, now)
+	}
+}
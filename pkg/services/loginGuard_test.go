@@ -0,0 +1,96 @@
+package services
+
+import (
+	"movierental/config"
+	"testing"
+	"time"
+)
+
+func newTestLoginGuard(cfg config.LoginProtectionConfig) (*LoginGuard, *time.Time) {
+	guard := NewLoginGuard(cfg)
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	guard.now = func() time.Time { return now }
+	return guard, &now
+}
+
+func TestLoginGuard_ProgressiveDelay(t *testing.T) {
+	guard, now := newTestLoginGuard(config.LoginProtectionConfig{
+		FreeAttempts:       2,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      100,
+		BaseDelaySeconds:   1,
+		MaxDelaySeconds:    4,
+	})
+
+	expected := []time.Duration{0, 0, time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second}
+	for i, want := range expected {
+		guard.RecordFailure("user@example.com", "10.0.0.1")
+		if got := guard.Check("user@example.com", "10.0.0.2"); got != want {
+			t.Errorf("After failure %d: expected wait %v, got %v", i+1, want, got)
+		}
+	}
+
+	*now = now.Add(5 * time.Second)
+	if got := guard.Check("user@example.com", "10.0.0.2"); got != 0 {
+		t.Errorf("Expected no wait once the delay elapsed, got %v", got)
+	}
+}
+
+func TestLoginGuard_Lockout(t *testing.T) {
+	guard, now := newTestLoginGuard(config.LoginProtectionConfig{
+		FreeAttempts:       5,
+		MaxAccountFailures: 3,
+		MaxIPFailures:      100,
+		LockoutSeconds:     600,
+	})
+
+	for i := 0; i < 3; i++ {
+		guard.RecordFailure("user@example.com", "10.0.0.1")
+	}
+	if got := guard.Check("user@example.com", "10.0.0.9"); got != 10*time.Minute {
+		t.Errorf("Expected 10m lockout, got %v", got)
+	}
+
+	*now = now.Add(10*time.Minute + time.Second)
+	if got := guard.Check("user@example.com", "10.0.0.9"); got != 0 {
+		t.Errorf("Expected lockout to expire, got %v", got)
+	}
+}
+
+func TestLoginGuard_PerIPLimit(t *testing.T) {
+	guard, _ := newTestLoginGuard(config.LoginProtectionConfig{
+		FreeAttempts:       5,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      3,
+	})
+
+	guard.RecordFailure("a@example.com", "10.0.0.1")
+	guard.RecordFailure("b@example.com", "10.0.0.1")
+	guard.RecordFailure("c@example.com", "10.0.0.1")
+
+	if got := guard.Check("d@example.com", "10.0.0.1"); got == 0 {
+		t.Error("Expected the IP to be locked out after spraying across accounts")
+	}
+	if got := guard.Check("d@example.com", "10.0.0.2"); got != 0 {
+		t.Errorf("Expected other IPs to be unaffected, got %v", got)
+	}
+}
+
+func TestLoginGuard_SuccessResetsAccount(t *testing.T) {
+	guard, _ := newTestLoginGuard(config.LoginProtectionConfig{
+		FreeAttempts:       1,
+		MaxAccountFailures: 10,
+		MaxIPFailures:      100,
+	})
+
+	guard.RecordFailure("user@example.com", "10.0.0.1")
+	guard.RecordFailure("user@example.com", "10.0.0.1")
+	if got := guard.Check("user@example.com", "10.0.0.2"); got == 0 {
+		t.Fatal("Expected a delay after exceeding the free attempts")
+	}
+	guard.RecordSuccess("user@example.com")
+
+	if got := guard.Check("user@example.com", "10.0.0.2"); got != 0 {
+		t.Errorf("Expected account history to be cleared, got wait %v", got)
+	}
+}
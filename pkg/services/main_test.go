@@ -0,0 +1,12 @@
+package services
+
+import (
+	"movierental/pkg/utils"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	utils.ConfigureTestSigningKey()
+	os.Exit(m.Run())
+}
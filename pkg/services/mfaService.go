@@ -0,0 +1,316 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movierental/pkg/models"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+
+	AuditEventMFAEnrolled         = "mfa_enrolled"
+	AuditEventMFAFailed           = "mfa_failed"
+	AuditEventMFARecoveryCodeUsed = "mfa_recovery_code_used"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("TOTP is already enabled for this account")
+	ErrMFANotPending     = errors.New("no pending TOTP enrollment. Start enrollment first")
+	ErrInvalidMFACode    = errors.New("invalid MFA code")
+)
+
+type MFAService struct {
+	Store    *repository.Store
+	Issuer   string
+	Sessions *SessionService
+	// LoginGuard, when set, throttles wrong codes per account and client IP.
+	// Sharing the guard of UserService gives password and code failures one
+	// budget.
+	LoginGuard *LoginGuard
+}
+
+func NewMFAService(store *repository.Store, issuer string, sessions *SessionService, loginGuard *LoginGuard) *MFAService {
+	if issuer == "" {
+		issuer = "Movie Rental"
+	}
+	return &MFAService{Store: store, Issuer: issuer, Sessions: sessionsOrDefault(sessions, store), LoginGuard: loginGuard}
+}
+
+// MFARequiredForRole reports whether the policy forces users with role to
+// enroll in MFA before they can use the API.
+func MFARequiredForRole(requiredRoles []string, role string) bool {
+	return slices.Contains(requiredRoles, role)
+}
+
+// EnrollTOTP starts (or restarts) enrollment by storing a new pending secret
+// and a fresh set of recovery codes. TOTP is only enforced after the user
+// proves possession of the secret through VerifyTOTPEnrollment.
+func (ms *MFAService) EnrollTOTP(ctx context.Context, userId string) (map[string]interface{}, error) {
+	user, err := ms.Store.Users.FindByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("user '%s' not found", userId)
+		}
+		return nil, fmt.Errorf("failed to retrieve user for MFA enrollment: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	recoveryCodes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = ms.Store.Transaction(ctx, func(tx *repository.Store) error {
+		if err := tx.Users.SetTOTPSecret(ctx, user.ID, secret); err != nil {
+			return err
+		}
+		entities := make([]models.MFARecoveryCode, 0, len(recoveryCodes))
+		for _, code := range recoveryCodes {
+			entities = append(entities, models.MFARecoveryCode{
+				ID:       uuid.New().String(),
+				UserID:   user.ID,
+				CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+			})
+		}
+		return tx.MFARecoveryCodes.ReplaceForUser(ctx, user.ID, entities)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store TOTP enrollment: %w", err)
+	}
+
+	return gin.H{
+		"message":        "Scan the otpauth URI with an authenticator app, then verify a code to enable TOTP.",
+		"otpauth_uri":    utils.TOTPURI(ms.Issuer, user.Email, secret),
+		"secret":         secret,
+		"recovery_codes": recoveryCodes,
+	}, nil
+}
+
+// VerifyTOTPEnrollment enables TOTP once the user submits a valid code for
+// the pending secret, and returns a fresh access token for the same session
+// without the enrollment restriction. Wrong codes are throttled like wrong
+// login codes.
+func (ms *MFAService) VerifyTOTPEnrollment(ctx context.Context, userId string, sessionId string, code string, client ClientInfo) (map[string]interface{}, error) {
+	user, err := ms.Store.Users.FindByID(ctx, userId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user for MFA verification: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotPending
+	}
+	if err := ms.checkGuard(ctx, user.ID, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	counter, ok := utils.ValidateTOTPCode(user.TOTPSecret, code, time.Now())
+	if !ok {
+		ms.registerFailure(ctx, user.ID, client.IPAddress, "enrollment_verification")
+		return nil, ErrInvalidMFACode
+	}
+	if ms.LoginGuard != nil {
+		ms.LoginGuard.RecordSuccess(userAccount(user.ID))
+	}
+
+	if err := ms.Store.Users.EnableTOTP(ctx, user.ID, counter); err != nil {
+		return nil, fmt.Errorf("failed to enable TOTP: %w", err)
+	}
+	recordAudit(ctx, ms.Store.AuditLogs, models.AuditLog{Event: AuditEventMFAEnrolled, UserID: user.ID})
+
+	token, err := issueAccessToken(user, false, sessionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token after MFA enrollment: %w", err)
+	}
+
+	return gin.H{
+		"message": "TOTP enabled successfully!",
+		"token":   token,
+	}, nil
+}
+
+// CompleteLogin finishes a two-step login using the MFA challenge token
+// returned by LoginUser and either a TOTP code or an unused recovery code.
+// Wrong codes count as failed logins of the account, and a token that has
+// completed a login is not accepted again.
+func (ms *MFAService) CompleteLogin(ctx context.Context, mfaToken string, code string, client ClientInfo) (map[string]interface{}, error) {
+	clientIP := client.IPAddress
+	challenge, err := utils.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, ErrInvalidMFACode
+	}
+	used, err := ms.Store.UsedMFATokens.IsUsed(ctx, challenge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check MFA token: %w", err)
+	}
+	if used {
+		return nil, ErrInvalidMFACode
+	}
+
+	user, err := ms.Store.Users.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user for MFA login: %w", err)
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrInvalidMFACode
+	}
+	if err := ms.checkGuard(ctx, user.ID, clientIP); err != nil {
+		return nil, err
+	}
+
+	// The token is claimed before a code is checked, in the same transaction,
+	// so a request that loses a race for the token cannot spend a recovery
+	// code. A wrong code rolls the claim back and the token may be retried.
+	var failure string
+	var usedRecoveryCode bool
+	err = ms.Store.Transaction(ctx, func(tx *repository.Store) error {
+		claimed, err := tx.UsedMFATokens.Claim(ctx, &models.UsedMFAToken{
+			ID:        challenge.ID,
+			UserID:    user.ID,
+			ExpiresAt: challenge.ExpiresAt,
+			UsedAt:    time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record MFA token use: %w", err)
+		}
+		if !claimed {
+			failure = "reused_token"
+			return ErrInvalidMFACode
+		}
+		usedRecoveryCode, failure, err = checkLoginCode(ctx, tx, user, code)
+		if err != nil {
+			return err
+		}
+		if failure != "" {
+			return ErrInvalidMFACode
+		}
+		return nil
+	})
+	if errors.Is(err, ErrInvalidMFACode) {
+		if failure == "reused_token" {
+			// Another request completed the login with this token first.
+			recordAudit(ctx, ms.Store.AuditLogs, models.AuditLog{Event: AuditEventMFAFailed, UserID: user.ID, IPAddress: clientIP, Detail: failure})
+		} else {
+			ms.registerFailure(ctx, user.ID, clientIP, failure)
+		}
+		return nil, ErrInvalidMFACode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedRecoveryCode {
+		recordAudit(ctx, ms.Store.AuditLogs, models.AuditLog{Event: AuditEventMFARecoveryCodeUsed, UserID: user.ID, IPAddress: clientIP})
+	}
+	if ms.LoginGuard != nil {
+		ms.LoginGuard.RecordSuccess(userAccount(user.ID))
+	}
+
+	response, err := ms.Sessions.issueSessionTokens(ctx, user, false, client)
+	if err != nil {
+		return nil, err
+	}
+	response["message"] = "Login successful!"
+	return response, nil
+}
+
+// checkLoginCode accepts a TOTP code newer than the last one used, or else
+// spends a matching recovery code. failure names why the code was rejected
+// and is empty when it was accepted.
+func checkLoginCode(ctx context.Context, tx *repository.Store, user models.User, code string) (usedRecoveryCode bool, failure string, err error) {
+	if counter, ok := utils.ValidateTOTPCode(user.TOTPSecret, code, time.Now()); ok {
+		// Only advance when the counter is newer, so a code cannot be replayed
+		// within its validity window.
+		advanced, err := tx.Users.AdvanceTOTPCounter(ctx, user.ID, counter)
+		if err != nil {
+			return false, "", fmt.Errorf("failed to record TOTP usage: %w", err)
+		}
+		if !advanced {
+			return false, "replayed_code", nil
+		}
+		return false, "", nil
+	}
+
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return false, "invalid_code", nil
+	}
+	consumed, err := tx.MFARecoveryCodes.Consume(ctx, user.ID, utils.HashToken(normalized), time.Now())
+	if err != nil {
+		return false, "", fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if !consumed {
+		return false, "invalid_code", nil
+	}
+	return true, "", nil
+}
+
+// checkGuard returns a LoginThrottledError while the account or client IP
+// is throttled.
+func (ms *MFAService) checkGuard(ctx context.Context, userId string, clientIP string) error {
+	if ms.LoginGuard == nil {
+		return nil
+	}
+	if wait := ms.LoginGuard.Check(userAccount(userId), clientIP); wait > 0 {
+		recordAudit(ctx, ms.Store.AuditLogs, models.AuditLog{Event: AuditEventMFAFailed, UserID: userId, IPAddress: clientIP, Detail: "throttled"})
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (ms *MFAService) registerFailure(ctx context.Context, userId string, clientIP string, detail string) {
+	recordAudit(ctx, ms.Store.AuditLogs, models.AuditLog{Event: AuditEventMFAFailed, UserID: userId, IPAddress: clientIP, Detail: detail})
+	if ms.LoginGuard != nil {
+		ms.LoginGuard.RecordFailure(userAccount(userId), clientIP)
+	}
+}
+
+// PurgeUsedTokens deletes the records of used MFA tokens that have expired,
+// which are rejected anyway.
+func (ms *MFAService) PurgeUsedTokens(ctx context.Context) error {
+	purged, err := ms.Store.UsedMFATokens.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to purge used MFA tokens: %w", err)
+	}
+	if purged > 0 {
+		slog.InfoContext(ctx, "Purged used MFA tokens", "count", purged)
+	}
+	return nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func generateRecoveryCodes(count int) ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+		}
+		encoded := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes = append(codes, encoded[:5]+"-"+encoded[5:])
+	}
+	return codes, nil
+}
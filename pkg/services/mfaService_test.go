@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/models"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"testing"
+	"time"
+)
+
+// unusedMFATokens reports every token as unused, as a request that checked
+// the token just before a concurrent login claimed it would see.
+type unusedMFATokens struct {
+	repository.UsedMFATokenRepository
+}
+
+func (unusedMFATokens) IsUsed(ctx context.Context, id string) (bool, error) {
+	return false, nil
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	t.Helper()
+	code, err := utils.GenerateTOTPCode(secret, utils.TOTPCounter(time.Now()))
+	if err != nil {
+		t.Fatalf("Failed to generate TOTP code: %v", err)
+	}
+	return code
+}
+
+func TestMFAService_EnrollAndVerify(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	mfaService := NewMFAService(store, "Test Issuer", nil, nil)
+	testUserID := "user-mfa-1"
+	utils.CreateTestUserAndCart(testDB, testUserID, "mfa1@example.com")
+
+	response, err := mfaService.EnrollTOTP(context.Background(), testUserID)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	secret, _ := response["secret"].(string)
+	if secret == "" {
+		t.Fatal("Expected a TOTP secret in the response")
+	}
+	if codes, ok := response["recovery_codes"].([]string); !ok || len(codes) != recoveryCodeCount {
+		t.Errorf("Expected %d recovery codes, got %v", recoveryCodeCount, response["recovery_codes"])
+	}
+
+	t.Run("Wrong code is rejected", func(t *testing.T) {
+		_, err := mfaService.VerifyTOTPEnrollment(context.Background(), testUserID, "", "000000", testClient)
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("Expected ErrInvalidMFACode, got: %v", err)
+		}
+	})
+
+	t.Run("Valid code enables TOTP", func(t *testing.T) {
+		response, err := mfaService.VerifyTOTPEnrollment(context.Background(), testUserID, "", currentTOTPCode(t, secret), testClient)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if token, ok := response["token"].(string); !ok || token == "" {
+			t.Errorf("Expected non-empty token, got: %v", response["token"])
+		}
+
+		var user models.User
+		testDB.Where("id = ?", testUserID).First(&user)
+		if !user.TOTPEnabled {
+			t.Error("Expected TOTP to be enabled in DB")
+		}
+	})
+
+	t.Run("Enrolling again is rejected", func(t *testing.T) {
+		_, err := mfaService.EnrollTOTP(context.Background(), testUserID)
+		if !errors.Is(err, ErrMFAAlreadyEnabled) {
+			t.Errorf("Expected ErrMFAAlreadyEnabled, got: %v", err)
+		}
+	})
+}
+
+func TestMFAService_CompleteLogin(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	mfaService := NewMFAService(store, "", nil, nil)
+	testUserID := "user-mfa-2"
+	utils.CreateTestUserAndCart(testDB, testUserID, "mfa2@example.com")
+
+	response, _ := mfaService.EnrollTOTP(context.Background(), testUserID)
+	secret := response["secret"].(string)
+	recoveryCodes := response["recovery_codes"].([]string)
+	testDB.Model(&models.User{}).Where("id = ?", testUserID).Update("totp_enabled", true)
+
+	newMFAToken := func(t *testing.T) string {
+		t.Helper()
+		token, err := utils.GenerateMFAToken(testUserID)
+		if err != nil {
+			t.Fatalf("Failed to generate MFA token: %v", err)
+		}
+		return token
+	}
+
+	t.Run("Valid TOTP code completes login", func(t *testing.T) {
+		response, err := mfaService.CompleteLogin(context.Background(), newMFAToken(t), currentTOTPCode(t, secret), testClient)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if token, ok := response["token"].(string); !ok || token == "" {
+			t.Errorf("Expected non-empty token, got: %v", response["token"])
+		}
+	})
+
+	t.Run("Replayed TOTP code is rejected", func(t *testing.T) {
+		_, err := mfaService.CompleteLogin(context.Background(), newMFAToken(t), currentTOTPCode(t, secret), testClient)
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("Expected ErrInvalidMFACode, got: %v", err)
+		}
+	})
+
+	t.Run("Recovery code works once", func(t *testing.T) {
+		if _, err := mfaService.CompleteLogin(context.Background(), newMFAToken(t), recoveryCodes[0], testClient); err != nil {
+			t.Fatalf("Expected recovery code to be accepted, got: %v", err)
+		}
+		if _, err := mfaService.CompleteLogin(context.Background(), newMFAToken(t), recoveryCodes[0], testClient); !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("Expected reused recovery code to be rejected, got: %v", err)
+		}
+	})
+
+	t.Run("MFA token completes one login only", func(t *testing.T) {
+		mfaToken := newMFAToken(t)
+		if _, err := mfaService.CompleteLogin(context.Background(), mfaToken, recoveryCodes[2], testClient); err != nil {
+			t.Fatalf("Expected recovery code to be accepted, got: %v", err)
+		}
+		if _, err := mfaService.CompleteLogin(context.Background(), mfaToken, recoveryCodes[3], testClient); !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("Expected the used MFA token to be rejected, got: %v", err)
+		}
+		var unused int64
+		testDB.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NULL", testUserID).Count(&unused)
+		if unused != int64(len(recoveryCodes)-2) {
+			t.Errorf("Expected the used MFA token not to spend a recovery code, %d of %d left", unused, len(recoveryCodes))
+		}
+	})
+
+	t.Run("Losing a race for the MFA token keeps the recovery code", func(t *testing.T) {
+		mfaToken := newMFAToken(t)
+		if _, err := mfaService.CompleteLogin(context.Background(), mfaToken, recoveryCodes[4], testClient); err != nil {
+			t.Fatalf("Expected recovery code to be accepted, got: %v", err)
+		}
+
+		racing := *store
+		racing.UsedMFATokens = unusedMFATokens{store.UsedMFATokens}
+		loser := NewMFAService(&racing, "", nil, nil)
+		if _, err := loser.CompleteLogin(context.Background(), mfaToken, recoveryCodes[5], testClient); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("Expected the claimed MFA token to be rejected, got: %v", err)
+		}
+		var used int64
+		testDB.Model(&models.MFARecoveryCode{}).Where("user_id = ? AND used_at IS NOT NULL", testUserID).Count(&used)
+		if used != 3 {
+			t.Errorf("Expected only the winning login to spend a recovery code, %d spent", used)
+		}
+	})
+
+	t.Run("Access token is not accepted as MFA token", func(t *testing.T) {
+		accessToken, _ := utils.GenerateAccessToken(utils.TokenClaims{UserID: testUserID, Email: "mfa2@example.com"})
+		_, err := mfaService.CompleteLogin(context.Background(), accessToken, recoveryCodes[1], testClient)
+		if !errors.Is(err, ErrInvalidMFACode) {
+			t.Errorf("Expected ErrInvalidMFACode, got: %v", err)
+		}
+	})
+}
+
+func TestMFAService_CompleteLoginLimitsWrongCodes(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+	guard := NewLoginGuard(config.LoginProtectionConfig{FreeAttempts: 2, MaxAccountFailures: 3, MaxIPFailures: 100, LockoutSeconds: 900})
+	mfaService := NewMFAService(store, "", nil, guard)
+	testUserID := "user-mfa-guard"
+	utils.CreateTestUserAndCart(testDB, testUserID, "mfa-guard@example.com")
+
+	response, _ := mfaService.EnrollTOTP(context.Background(), testUserID)
+	secret := response["secret"].(string)
+	testDB.Model(&models.User{}).Where("id = ?", testUserID).Update("totp_enabled", true)
+
+	// One MFA token is enough to try every code; the guard has to stop it.
+	mfaToken, _ := utils.GenerateMFAToken(testUserID)
+	for i := 0; i < 3; i++ {
+		if _, err := mfaService.CompleteLogin(context.Background(), mfaToken, "wrong-code", testClient); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("Attempt %d: expected ErrInvalidMFACode, got: %v", i+1, err)
+		}
+	}
+
+	_, err := mfaService.CompleteLogin(context.Background(), mfaToken, currentTOTPCode(t, secret), testClient)
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Expected a valid code to be refused once the account is locked, got: %v", err)
+	}
+	var sessions int64
+	testDB.Model(&models.Session{}).Where("user_id = ?", testUserID).Count(&sessions)
+	if sessions != 0 {
+		t.Errorf("Expected no session to be issued, got %d", sessions)
+	}
+}
+
+func TestMFAService_VerifyTOTPEnrollmentLimitsWrongCodes(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+	guard := NewLoginGuard(config.LoginProtectionConfig{FreeAttempts: 2, MaxAccountFailures: 3, MaxIPFailures: 100, LockoutSeconds: 900})
+	mfaService := NewMFAService(store, "", nil, guard)
+	testUserID := "user-mfa-enroll-guard"
+	utils.CreateTestUserAndCart(testDB, testUserID, "mfa-enroll-guard@example.com")
+
+	response, _ := mfaService.EnrollTOTP(context.Background(), testUserID)
+	secret := response["secret"].(string)
+
+	for i := 0; i < 3; i++ {
+		if _, err := mfaService.VerifyTOTPEnrollment(context.Background(), testUserID, "", "000000", testClient); !errors.Is(err, ErrInvalidMFACode) {
+			t.Fatalf("Attempt %d: expected ErrInvalidMFACode, got: %v", i+1, err)
+		}
+	}
+
+	_, err := mfaService.VerifyTOTPEnrollment(context.Background(), testUserID, "", currentTOTPCode(t, secret), testClient)
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Expected a valid code to be refused once the account is locked, got: %v", err)
+	}
+	var user models.User
+	testDB.Where("id = ?", testUserID).First(&user)
+	if user.TOTPEnabled {
+		t.Error("Expected TOTP to stay disabled")
+	}
+}
@@ -1,12 +1,13 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"movierental/pkg/movie/movieExternalApi"
 )
 
 type APIClientInterface interface {
-	Get(path string, queryParams map[string]string, result interface{}) error
+	Get(ctx context.Context, path string, queryParams map[string]string, result interface{}) error
 }
 
 type MovieService struct {
@@ -17,10 +18,10 @@ func NewMovieService(client APIClientInterface) *MovieService {
 	return &MovieService{APIClient: client}
 }
 
-func (ms *MovieService) ListAllMovies(queryParams map[string]string) ([]movieExternalApi.Movie, error) {
+func (ms *MovieService) ListAllMovies(ctx context.Context, queryParams map[string]string) ([]movieExternalApi.Movie, error) {
 	var moviesResponse movieExternalApi.ListMoviesResponse
 
-	err := ms.APIClient.Get("/list_movies.json", queryParams, &moviesResponse)
+	err := ms.APIClient.Get(ctx, "/list_movies.json", queryParams, &moviesResponse)
 	if err != nil {
 		return nil, fmt.Errorf("error calling external RapidAPI: %w", err)
 	}
@@ -32,13 +33,13 @@ func (ms *MovieService) ListAllMovies(queryParams map[string]string) ([]movieExt
 	return moviesResponse.Data.Movies, nil
 }
 
-func (ms *MovieService) GetMovieDetails(movieId string) (movieExternalApi.Movie, error) {
+func (ms *MovieService) GetMovieDetails(ctx context.Context, movieId string) (movieExternalApi.Movie, error) {
 	queryParams := map[string]string{
 		"movie_id": movieId,
 	}
 	var moviesResponse movieExternalApi.MovieResponse
 
-	err := ms.APIClient.Get("/movie_details.json", queryParams, &moviesResponse)
+	err := ms.APIClient.Get(ctx, "/movie_details.json", queryParams, &moviesResponse)
 	if err != nil {
 		return movieExternalApi.Movie{}, fmt.Errorf("error calling external RapidAPI: %w", err)
 	}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -11,7 +12,7 @@ type MockAPIClient struct {
 	MockGet func(path string, queryParams map[string]string, result interface{}) error
 }
 
-func (m *MockAPIClient) Get(path string, queryParams map[string]string, result interface{}) error {
+func (m *MockAPIClient) Get(ctx context.Context, path string, queryParams map[string]string, result interface{}) error {
 	if m.MockGet != nil {
 		return m.MockGet(path, queryParams, result)
 	}
@@ -45,7 +46,7 @@ func TestMovieService_ListAllMovies(t *testing.T) {
 		}
 
 		queryParams := map[string]string{"limit": "2", "page": "1"}
-		movies, err := movieService.ListAllMovies(queryParams)
+		movies, err := movieService.ListAllMovies(context.Background(), queryParams)
 
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
@@ -78,7 +79,7 @@ func TestMovieService_ListAllMovies(t *testing.T) {
 		}
 
 		queryParams := map[string]string{"limit": "1", "page": "1"}
-		_, err := movieService.ListAllMovies(queryParams)
+		_, err := movieService.ListAllMovies(context.Background(), queryParams)
 
 		if err == nil {
 			t.Error("Expected an error, got none")
@@ -95,7 +96,7 @@ func TestMovieService_ListAllMovies(t *testing.T) {
 		}
 
 		queryParams := map[string]string{"limit": "1", "page": "1"}
-		_, err := movieService.ListAllMovies(queryParams)
+		_, err := movieService.ListAllMovies(context.Background(), queryParams)
 
 		if err == nil {
 			t.Error("Expected an error, got none")
@@ -131,7 +132,7 @@ func TestMovieService_GetMovieDetails(t *testing.T) {
 			return nil
 		}
 
-		movie, err := movieService.GetMovieDetails("123")
+		movie, err := movieService.GetMovieDetails(context.Background(), "123")
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -148,7 +149,7 @@ func TestMovieService_GetMovieDetails(t *testing.T) {
 			return errors.New("connection refused")
 		}
 
-		_, err := movieService.GetMovieDetails("123")
+		_, err := movieService.GetMovieDetails(context.Background(), "123")
 		if err == nil {
 			t.Error("Expected an error, got none")
 		}
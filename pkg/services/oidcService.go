@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"movierental/config"
+	"movierental/pkg/metrics"
+	"movierental/pkg/models"
+	"movierental/pkg/oidc"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditEventOIDCLogin          = "oidc_login"
+	AuditEventOIDCIdentityLinked = "oidc_identity_linked"
+)
+
+var (
+	ErrUnknownOIDCProvider = errors.New("unknown identity provider")
+	ErrInvalidOIDCState    = errors.New("invalid or expired login state. Please start the login again")
+	ErrOIDCEmailConflict   = errors.New("an account with this email already exists. Log in with your password to continue")
+)
+
+var usernameDisallowed = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+type OIDCService struct {
+	Store     *repository.Store
+	Providers map[string]*oidc.Provider
+	MFAPolicy config.MFAConfig
+	Sessions  *SessionService
+}
+
+func NewOIDCService(store *repository.Store, providerConfigs map[string]config.OIDCProviderConfig, mfaPolicy config.MFAConfig, sessions *SessionService) *OIDCService {
+	providers := make(map[string]*oidc.Provider, len(providerConfigs))
+	for name, cfg := range providerConfigs {
+		providers[name] = oidc.NewProvider(name, cfg)
+	}
+	return &OIDCService{Store: store, Providers: providers, MFAPolicy: mfaPolicy, Sessions: sessionsOrDefault(sessions, store)}
+}
+
+// StartLogin returns the provider authorization URL and a signed state token
+// carrying the state, nonce and PKCE verifier for the callback.
+func (ois *OIDCService) StartLogin(ctx context.Context, providerName string) (string, string, error) {
+	provider, ok := ois.Providers[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	state, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateRandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.GenerateRandomToken(48)
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, oidc.CodeChallengeS256(codeVerifier))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build authorization URL: %w", err)
+	}
+	stateToken, err := utils.GenerateOIDCStateToken(utils.OIDCLoginState{
+		Provider:     providerName,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to sign login state: %w", err)
+	}
+	return authURL, stateToken, nil
+}
+
+// CompleteLogin handles the provider callback: it checks the state, redeems
+// the code, verifies the ID token and signs in the linked user, linking or
+// creating one on first login.
+func (ois *OIDCService) CompleteLogin(ctx context.Context, providerName string, code string, state string, stateToken string, client ClientInfo) (map[string]interface{}, error) {
+	provider, ok := ois.Providers[providerName]
+	if !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+
+	loginState, err := utils.VerifyOIDCStateToken(stateToken)
+	if err != nil || loginState.Provider != providerName || loginState.State != state {
+		return nil, ErrInvalidOIDCState
+	}
+
+	tokens, err := provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify identity: %w", err)
+	}
+
+	user, created, err := ois.resolveUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, err
+	}
+	recordAudit(ctx, ois.Store.AuditLogs, models.AuditLog{Event: AuditEventOIDCLogin, UserID: user.ID, Identifier: providerName + ":" + claims.Subject, IPAddress: client.IPAddress})
+
+	response, err := loginResponse(ctx, user, ois.MFAPolicy, ois.Sessions, client)
+	if err != nil {
+		return nil, err
+	}
+	response["user_id"] = user.ID
+	response["created"] = created
+	return response, nil
+}
+
+// resolveUser finds the user linked to the external identity. Without a link
+// it links an existing account with the same email only if the provider
+// verified that email, and otherwise creates a new user and cart.
+func (ois *OIDCService) resolveUser(ctx context.Context, providerName string, claims *oidc.IDTokenClaims) (models.User, bool, error) {
+	var user models.User
+	var created, linked bool
+
+	err := ois.Store.Transaction(ctx, func(tx *repository.Store) error {
+		identity, err := tx.UserIdentities.FindByProviderSubject(ctx, providerName, claims.Subject)
+		if err == nil {
+			if err := tx.UserIdentities.UpdateLastLogin(ctx, identity.ID, time.Now()); err != nil {
+				return fmt.Errorf("failed to update identity: %w", err)
+			}
+			user, err = tx.Users.FindByID(ctx, identity.UserID)
+			return err
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to look up identity: %w", err)
+		}
+
+		email := utils.NormalizeEmail(claims.Email)
+		if email != "" {
+			existing, err := tx.Users.FindByEmail(ctx, email)
+			switch {
+			case err == nil && !claims.EmailVerified:
+				return ErrOIDCEmailConflict
+			case err == nil:
+				user = existing
+			case !errors.Is(err, repository.ErrNotFound):
+				return fmt.Errorf("failed to look up user by email: %w", err)
+			}
+		}
+
+		if user.ID == "" {
+			if email == "" {
+				email = fmt.Sprintf("%s@%s.oidc.invalid", claims.Subject, providerName)
+			}
+			username, err := availableUsername(ctx, tx, claims)
+			if err != nil {
+				return err
+			}
+			user = models.User{
+				ID:       uuid.New().String(),
+				Username: username,
+				Email:    email,
+				Role:     models.RoleUser,
+			}
+			if _, err := createUserWithCart(ctx, tx, &user); err != nil {
+				return err
+			}
+			created = true
+		}
+
+		now := time.Now()
+		identity = models.UserIdentity{
+			ID:          uuid.New().String(),
+			UserID:      user.ID,
+			Provider:    providerName,
+			Subject:     claims.Subject,
+			Email:       email,
+			CreatedAt:   now,
+			LastLoginAt: now,
+		}
+		if err := tx.UserIdentities.Create(ctx, &identity); err != nil {
+			return fmt.Errorf("failed to link identity: %w", err)
+		}
+		linked = true
+		return nil
+	})
+	if err != nil {
+		return models.User{}, false, err
+	}
+	if created {
+		metrics.Signups.WithLabelValues("oidc").Inc()
+	}
+	if linked {
+		recordAudit(ctx, ois.Store.AuditLogs, models.AuditLog{Event: AuditEventOIDCIdentityLinked, UserID: user.ID, Identifier: providerName + ":" + claims.Subject})
+	}
+	return user, created, nil
+}
+
+// availableUsername derives a username from the provider's claims, adding a
+// random suffix when the preferred one is already taken.
+func availableUsername(ctx context.Context, tx *repository.Store, claims *oidc.IDTokenClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base = claims.Email
+	}
+	base, _, _ = strings.Cut(base, "@")
+	base = usernameDisallowed.ReplaceAllString(utils.NormalizeUsername(base), "")
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		taken, err := tx.Users.UsernameExists(ctx, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username availability: %w", err)
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = base + "-" + uuid.New().String()[:8]
+	}
+	return "", errors.New("failed to find an available username")
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/models"
+	"movierental/pkg/oidc/oidctest"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+// followAuthorization runs the provider's authorization step and returns the
+// code and state it redirects back with.
+func followAuthorization(t *testing.T, authURL string) (string, string) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Authorization request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect location: %v", err)
+	}
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func oidcLogin(t *testing.T, oidcService *OIDCService) (map[string]interface{}, error) {
+	t.Helper()
+	authURL, stateToken, err := oidcService.StartLogin(context.Background(), "mock")
+	if err != nil {
+		t.Fatalf("StartLogin failed: %v", err)
+	}
+	code, state := followAuthorization(t, authURL)
+	return oidcService.CompleteLogin(context.Background(), "mock", code, state, stateToken, testClient)
+}
+
+func TestOIDCService_CompleteLogin(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	server := oidctest.NewServer("movierental")
+	defer server.Close()
+
+	oidcService := NewOIDCService(store, map[string]config.OIDCProviderConfig{
+		"mock": {
+			IssuerURL:   server.URL,
+			ClientID:    "movierental",
+			RedirectURL: "http://localhost:8080/auth/oidc/mock/callback",
+		},
+	}, config.MFAConfig{}, nil)
+
+	t.Run("First login creates user and cart", func(t *testing.T) {
+		server.SetUser(oidctest.User{Subject: "sub-new", Email: "New.User@example.com", EmailVerified: true, PreferredUsername: "newuser"})
+		response, err := oidcLogin(t, oidcService)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["created"] != true {
+			t.Errorf("Expected created=true, got %v", response["created"])
+		}
+		if token, ok := response["token"].(string); !ok || token == "" {
+			t.Errorf("Expected non-empty token, got: %v", response["token"])
+		}
+
+		userID, _ := response["user_id"].(string)
+		var user models.User
+		testDB.Where("id = ?", userID).First(&user)
+		if user.Email != "new.user@example.com" || user.Username != "newuser" {
+			t.Errorf("Unexpected user created: %+v", user)
+		}
+		var cartCount int64
+		testDB.Table("carts").Where("user_id = ?", userID).Count(&cartCount)
+		if cartCount != 1 {
+			t.Errorf("Expected a cart for the new user, got %d", cartCount)
+		}
+	})
+
+	t.Run("Second login reuses the linked user", func(t *testing.T) {
+		server.SetUser(oidctest.User{Subject: "sub-new", Email: "new.user@example.com", EmailVerified: true})
+		response, err := oidcLogin(t, oidcService)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["created"] != false {
+			t.Errorf("Expected created=false, got %v", response["created"])
+		}
+		var identityCount int64
+		testDB.Model(&models.UserIdentity{}).Where("subject = ?", "sub-new").Count(&identityCount)
+		if identityCount != 1 {
+			t.Errorf("Expected exactly one linked identity, got %d", identityCount)
+		}
+	})
+
+	t.Run("Verified email links existing account", func(t *testing.T) {
+		utils.CreateTestUserAndCart(testDB, "user-oidc-existing", "existing@example.com")
+		server.SetUser(oidctest.User{Subject: "sub-existing", Email: "existing@example.com", EmailVerified: true})
+		response, err := oidcLogin(t, oidcService)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["user_id"] != "user-oidc-existing" || response["created"] != false {
+			t.Errorf("Expected link to the existing user, got %v", response)
+		}
+	})
+
+	t.Run("Unverified email conflicts with existing account", func(t *testing.T) {
+		utils.CreateTestUserAndCart(testDB, "user-oidc-unverified", "unverified@example.com")
+		server.SetUser(oidctest.User{Subject: "sub-unverified", Email: "unverified@example.com", EmailVerified: false})
+		_, err := oidcLogin(t, oidcService)
+		if !errors.Is(err, ErrOIDCEmailConflict) {
+			t.Errorf("Expected ErrOIDCEmailConflict, got: %v", err)
+		}
+	})
+
+	t.Run("State mismatch is rejected", func(t *testing.T) {
+		authURL, stateToken, err := oidcService.StartLogin(context.Background(), "mock")
+		if err != nil {
+			t.Fatalf("StartLogin failed: %v", err)
+		}
+		code, _ := followAuthorization(t, authURL)
+		_, err = oidcService.CompleteLogin(context.Background(), "mock", code, "forged-state", stateToken, testClient)
+		if !errors.Is(err, ErrInvalidOIDCState) {
+			t.Errorf("Expected ErrInvalidOIDCState, got: %v", err)
+		}
+	})
+
+	t.Run("Unknown provider", func(t *testing.T) {
+		if _, _, err := oidcService.StartLogin(context.Background(), "nope"); !errors.Is(err, ErrUnknownOIDCProvider) {
+			t.Errorf("Expected ErrUnknownOIDCProvider, got: %v", err)
+		}
+	})
+}
@@ -1,23 +1,50 @@
 package services
 
 import (
-	"movierental/pkg/models"
+	"context"
 	"movierental/pkg/models/requests"
 	"movierental/pkg/movie/movieExternalApi"
 )
 
 type UserServiceInterface interface {
-	CreateUser(userReq requests.CreateUser) (map[string]interface{}, error)
-	LoginUser(loginReq models.User) (map[string]interface{}, error)
+	CreateUser(ctx context.Context, userReq requests.CreateUser) (map[string]interface{}, error)
+	LoginUser(ctx context.Context, loginReq requests.Login, client ClientInfo) (map[string]interface{}, error)
+	ChangePassword(ctx context.Context, userId string, changeReq requests.ChangePassword) (map[string]interface{}, error)
+	CreatePasswordReset(ctx context.Context, adminId string, userId string) (map[string]interface{}, error)
+	ResetPassword(ctx context.Context, resetReq requests.ResetPassword) (map[string]interface{}, error)
+	CreateScopedToken(ctx context.Context, userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error)
+}
+
+type MFAServiceInterface interface {
+	EnrollTOTP(ctx context.Context, userId string) (map[string]interface{}, error)
+	VerifyTOTPEnrollment(ctx context.Context, userId string, sessionId string, code string, client ClientInfo) (map[string]interface{}, error)
+	CompleteLogin(ctx context.Context, mfaToken string, code string, client ClientInfo) (map[string]interface{}, error)
+}
+
+type OIDCServiceInterface interface {
+	StartLogin(ctx context.Context, providerName string) (string, string, error)
+	CompleteLogin(ctx context.Context, providerName string, code string, state string, stateToken string, client ClientInfo) (map[string]interface{}, error)
+}
+
+type SessionServiceInterface interface {
+	Refresh(ctx context.Context, refreshToken string, client ClientInfo) (map[string]interface{}, error)
+	ListSessions(ctx context.Context, userId string, currentSessionId string) (map[string]interface{}, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string) (map[string]interface{}, error)
+}
+
+type APIKeyServiceInterface interface {
+	CreateAPIKey(ctx context.Context, userId string, keyReq requests.CreateAPIKey) (map[string]interface{}, error)
+	ListAPIKeys(ctx context.Context, userId string) (map[string]interface{}, error)
+	RevokeAPIKey(ctx context.Context, userId string, keyId string) (map[string]interface{}, error)
 }
 
 type MovieServiceInterface interface {
-	ListAllMovies(queryParams map[string]string) ([]movieExternalApi.Movie, error)
-	GetMovieDetails(movieId string) (movieExternalApi.Movie, error)
+	ListAllMovies(ctx context.Context, queryParams map[string]string) ([]movieExternalApi.Movie, error)
+	GetMovieDetails(ctx context.Context, movieId string) (movieExternalApi.Movie, error)
 }
 
 type CartServiceInterface interface {
-	RetrieveCart(userId interface{}) (requests.Cart, error)
-	AddToCart(userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error)
-	RemoveFromCart(userId interface{}, movieID int) (map[string]interface{}, error)
+	RetrieveCart(ctx context.Context, userId interface{}) (requests.Cart, error)
+	AddToCart(ctx context.Context, userId interface{}, movieItem requests.CartMovieItem) (map[string]interface{}, error)
+	RemoveFromCart(ctx context.Context, userId interface{}, movieID int) (map[string]interface{}, error)
 }
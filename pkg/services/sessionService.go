@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"movierental/config"
+	"movierental/pkg/metrics"
+	"movierental/pkg/models"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+	// sessionLastSeenResolution bounds how often last_seen_at is written for
+	// an active session.
+	sessionLastSeenResolution = time.Minute
+
+	AuditEventSessionRevoked     = "session_revoked"
+	AuditEventAllSessionsRevoked = "all_sessions_revoked"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrSessionTerminated   = errors.New("session has been terminated. Please log in again")
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// ClientInfo describes the device a request comes from.
+type ClientInfo struct {
+	IPAddress string
+	UserAgent string
+}
+
+type SessionService struct {
+	Store           *repository.Store
+	RefreshTokenTTL time.Duration
+	MFAPolicy       config.MFAConfig
+}
+
+func NewSessionService(store *repository.Store, cfg config.SessionConfig, mfaPolicy config.MFAConfig) *SessionService {
+	return &SessionService{
+		Store:           store,
+		RefreshTokenTTL: time.Duration(cfg.RefreshTokenTTLHours) * time.Hour,
+		MFAPolicy:       mfaPolicy,
+	}
+}
+
+// sessionsOrDefault returns sessions, or a SessionService with the default
+// settings on store when none was configured.
+func sessionsOrDefault(sessions *SessionService, store *repository.Store) *SessionService {
+	if sessions != nil {
+		return sessions
+	}
+	return &SessionService{Store: store}
+}
+
+func (ss *SessionService) refreshTokenTTL() time.Duration {
+	if ss.RefreshTokenTTL <= 0 {
+		return defaultRefreshTokenTTL
+	}
+	return ss.RefreshTokenTTL
+}
+
+// startSession records a new session for user and returns it with its
+// refresh token.
+func (ss *SessionService) startSession(ctx context.Context, user models.User, client ClientInfo) (models.Session, string, error) {
+	refreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return models.Session{}, "", err
+	}
+	now := time.Now()
+	session := models.Session{
+		ID:               uuid.New().String(),
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		CreatedAt:        now,
+		LastSeenAt:       now,
+		ExpiresAt:        now.Add(ss.refreshTokenTTL()),
+	}
+	if err := ss.Store.Sessions.Create(ctx, &session); err != nil {
+		return models.Session{}, "", fmt.Errorf("failed to create session: %w", err)
+	}
+	metrics.Logins.Inc()
+	return session, refreshToken, nil
+}
+
+// issueSessionTokens starts a session for user and returns an access token
+// bound to it together with the session's refresh token.
+func (ss *SessionService) issueSessionTokens(ctx context.Context, user models.User, mfaEnrollmentRequired bool, client ClientInfo) (gin.H, error) {
+	session, refreshToken, err := ss.startSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	token, err := issueAccessToken(user, mfaEnrollmentRequired, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token for login: %w", err)
+	}
+	return gin.H{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"session_id":    session.ID,
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access token and rotates the
+// refresh token, so each one can be used only once.
+func (ss *SessionService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (map[string]interface{}, error) {
+	session, err := ss.Store.Sessions.FindActiveByRefreshHash(ctx, utils.HashToken(refreshToken), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to retrieve session: %w", err)
+	}
+
+	user, err := ss.Store.Users.FindByID(ctx, session.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to retrieve user for session: %w", err)
+	}
+
+	newRefreshToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := ss.Store.Sessions.Rotate(ctx, session.ID, session.RefreshTokenHash, utils.HashToken(newRefreshToken),
+		client.IPAddress, client.UserAgent, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		// Another request rotated the token first.
+		return nil, ErrInvalidRefreshToken
+	}
+
+	enrollmentRequired := !user.TOTPEnabled && MFARequiredForRole(ss.MFAPolicy.RequiredRoles, user.Role)
+	token, err := issueAccessToken(user, enrollmentRequired, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token for refresh: %w", err)
+	}
+
+	response := gin.H{
+		"message":       "Token refreshed",
+		"token":         token,
+		"refresh_token": newRefreshToken,
+	}
+	if enrollmentRequired {
+		response["mfa_enrollment_required"] = true
+	}
+	return response, nil
+}
+
+// ListSessions returns the user's active sessions, marking the one the
+// request was made with.
+func (ss *SessionService) ListSessions(ctx context.Context, userId string, currentSessionId string) (map[string]interface{}, error) {
+	sessions, err := ss.Store.Sessions.ListActive(ctx, userId, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions: %w", err)
+	}
+
+	views := make([]gin.H, 0, len(sessions))
+	for _, session := range sessions {
+		views = append(views, gin.H{
+			"id":           session.ID,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID == currentSessionId,
+		})
+	}
+	return gin.H{"sessions": views}, nil
+}
+
+// RevokeSession terminates one of the user's sessions. Its refresh token
+// stops working immediately, and so do access tokens issued for it.
+func (ss *SessionService) RevokeSession(ctx context.Context, userId string, sessionId string) (map[string]interface{}, error) {
+	revoked, err := ss.Store.Sessions.RevokeForUser(ctx, sessionId, userId, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return nil, ErrSessionNotFound
+	}
+	recordAudit(ctx, ss.Store.AuditLogs, models.AuditLog{Event: AuditEventSessionRevoked, UserID: userId, Identifier: sessionId})
+
+	return gin.H{"message": "Session terminated", "id": sessionId}, nil
+}
+
+// RevokeAllSessions terminates every session of the user, signing them out
+// everywhere. revokedBy names who asked for it in the audit log.
+func (ss *SessionService) RevokeAllSessions(ctx context.Context, userId string, revokedBy string) (map[string]interface{}, error) {
+	if err := ss.Store.Sessions.RevokeAllForUser(ctx, userId, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	recordAudit(ctx, ss.Store.AuditLogs, models.AuditLog{Event: AuditEventAllSessionsRevoked, UserID: userId, Detail: "revoked_by:" + revokedBy})
+
+	return gin.H{"message": "All sessions terminated", "user_id": userId}, nil
+}
+
+// VerifySession reports whether access tokens for sessionId are still
+// accepted, and records the session as seen.
+func (ss *SessionService) VerifySession(ctx context.Context, sessionId string) error {
+	session, err := ss.Store.Sessions.FindByID(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrSessionTerminated
+		}
+		return fmt.Errorf("failed to retrieve session: %w", err)
+	}
+	now := time.Now()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return ErrSessionTerminated
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionLastSeenResolution {
+		if err := ss.Store.Sessions.UpdateLastSeen(ctx, session.ID, now); err != nil {
+			slog.WarnContext(ctx, "Failed to update last seen time of session", "session_id", session.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpiredSessions deletes sessions whose refresh token has expired.
+// Their tokens are rejected anyway; this only keeps the table from growing.
+func (ss *SessionService) PurgeExpiredSessions(ctx context.Context) error {
+	purged, err := ss.Store.Sessions.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to purge expired sessions: %w", err)
+	}
+	if purged > 0 {
+		slog.InfoContext(ctx, "Purged expired sessions", "count", purged)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"movierental/config"
+	"movierental/pkg/models"
+	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
+	"movierental/pkg/utils"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var testClient = ClientInfo{IPAddress: "127.0.0.1", UserAgent: "go-test"}
+
+func TestSessionService(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	sessionService := NewSessionService(store, config.SessionConfig{RefreshTokenTTLHours: 24}, config.MFAConfig{})
+	userService := &UserService{Store: store, Sessions: sessionService}
+
+	hashedPassword, _ := utils.HashPassword("correctpassword")
+	testDB.Create(&models.User{ID: "user-session", Username: "sessionuser", Email: "session@example.com", Password: hashedPassword})
+
+	login := func(t *testing.T, userAgent string) map[string]interface{} {
+		t.Helper()
+		response, err := userService.LoginUser(context.Background(), requests.Login{Email: "session@example.com", Password: "correctpassword"}, ClientInfo{IPAddress: "127.0.0.1", UserAgent: userAgent})
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		return response
+	}
+
+	laptop := login(t, "laptop")
+	phone := login(t, "phone")
+
+	t.Run("Access token is bound to the session", func(t *testing.T) {
+		claims, err := utils.ParseAccessToken(laptop["token"].(string))
+		if err != nil {
+			t.Fatalf("Expected a valid access token, got: %v", err)
+		}
+		if claims.SessionID != laptop["session_id"] {
+			t.Errorf("Expected session %v in token, got %q", laptop["session_id"], claims.SessionID)
+		}
+		if err := sessionService.VerifySession(context.Background(), claims.SessionID); err != nil {
+			t.Errorf("Expected active session, got: %v", err)
+		}
+	})
+
+	t.Run("List marks the current session", func(t *testing.T) {
+		response, err := sessionService.ListSessions(context.Background(), "user-session", laptop["session_id"].(string))
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		sessions, _ := response["sessions"].([]gin.H)
+		if len(sessions) != 2 {
+			t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+		}
+		for _, session := range sessions {
+			if current := session["id"] == laptop["session_id"]; session["current"] != current {
+				t.Errorf("Unexpected current flag for session %v", session)
+			}
+		}
+	})
+
+	t.Run("Refresh rotates the refresh token", func(t *testing.T) {
+		refreshToken := laptop["refresh_token"].(string)
+		response, err := sessionService.Refresh(context.Background(), refreshToken, testClient)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["refresh_token"] == refreshToken {
+			t.Error("Expected a new refresh token")
+		}
+		if _, err := sessionService.Refresh(context.Background(), refreshToken, testClient); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Expected the old refresh token to be rejected, got: %v", err)
+		}
+		laptop["refresh_token"] = response["refresh_token"]
+	})
+
+	t.Run("Revoked session rejects tokens", func(t *testing.T) {
+		phoneSession := phone["session_id"].(string)
+		if _, err := sessionService.RevokeSession(context.Background(), "someone-else", phoneSession); !errors.Is(err, ErrSessionNotFound) {
+			t.Errorf("Expected ErrSessionNotFound for another user, got: %v", err)
+		}
+		if _, err := sessionService.RevokeSession(context.Background(), "user-session", phoneSession); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := sessionService.VerifySession(context.Background(), phoneSession); !errors.Is(err, ErrSessionTerminated) {
+			t.Errorf("Expected ErrSessionTerminated, got: %v", err)
+		}
+		if _, err := sessionService.Refresh(context.Background(), phone["refresh_token"].(string), testClient); !errors.Is(err, ErrInvalidRefreshToken) {
+			t.Errorf("Expected ErrInvalidRefreshToken, got: %v", err)
+		}
+		if err := sessionService.VerifySession(context.Background(), laptop["session_id"].(string)); err != nil {
+			t.Errorf("Expected other sessions to stay active, got: %v", err)
+		}
+	})
+
+	t.Run("Purge removes only expired sessions", func(t *testing.T) {
+		laptopSession := laptop["session_id"].(string)
+		testDB.Model(&models.Session{}).Where("id = ?", phone["session_id"]).Update("expires_at", time.Now().Add(-time.Minute))
+
+		if err := sessionService.PurgeExpiredSessions(context.Background()); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var remaining []models.Session
+		testDB.Where("user_id = ?", "user-session").Find(&remaining)
+		if len(remaining) != 1 || remaining[0].ID != laptopSession {
+			t.Errorf("Expected only the laptop session to remain, got %d sessions", len(remaining))
+		}
+	})
+
+	t.Run("Revoking all sessions signs the user out everywhere", func(t *testing.T) {
+		tablet := login(t, "tablet")
+		if _, err := sessionService.RevokeAllSessions(context.Background(), "user-session", "operator"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		for _, session := range []interface{}{laptop["session_id"], tablet["session_id"]} {
+			if err := sessionService.VerifySession(context.Background(), session.(string)); !errors.Is(err, ErrSessionTerminated) {
+				t.Errorf("Expected session %v to be terminated, got: %v", session, err)
+			}
+		}
+	})
+}
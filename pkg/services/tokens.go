@@ -0,0 +1,17 @@
+package services
+
+import (
+	"movierental/pkg/models"
+	"movierental/pkg/utils"
+)
+
+func issueAccessToken(user models.User, mfaEnrollmentRequired bool, sessionId string) (string, error) {
+	return utils.GenerateAccessToken(utils.TokenClaims{
+		UserID:                user.ID,
+		Email:                 user.Email,
+		Role:                  user.Role,
+		MFAEnrollmentRequired: mfaEnrollmentRequired,
+		Scopes:                utils.DefaultScopesForRole(user.Role),
+		SessionID:             sessionId,
+	})
+}
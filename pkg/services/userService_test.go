@@ -1,10 +1,14 @@
 package services
 
 import (
-	"movierental/pkg/database"
+	"context"
+	"errors"
+	"movierental/config"
 	"movierental/pkg/models"
 	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
 	"movierental/pkg/utils"
+	"strings"
 	"testing"
 )
 
@@ -12,11 +16,9 @@ func TestUserService_CreateUser(t *testing.T) {
 	testDB := utils.SetupTestDB()
 	defer utils.ClearTestDB(testDB)
 
-	originalDB := database.DB
-	database.DB = testDB
-	defer func() { database.DB = originalDB }()
+	store := repository.NewStore(testDB)
 
-	userService := &UserService{}
+	userService := &UserService{Store: store}
 
 	t.Run("Successful user creation", func(t *testing.T) {
 		userReq := requests.CreateUser{
@@ -24,7 +26,7 @@ func TestUserService_CreateUser(t *testing.T) {
 			Email:    "test@example.com",
 			Password: "password123",
 		}
-		response, err := userService.CreateUser(userReq)
+		response, err := userService.CreateUser(context.Background(), userReq)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -56,17 +58,75 @@ func TestUserService_CreateUser(t *testing.T) {
 			t.Errorf("Expected cart to exist in DB, got error: %v", err)
 		}
 	})
+
+	t.Run("Email and username are normalized", func(t *testing.T) {
+		response, err := userService.CreateUser(context.Background(), requests.CreateUser{
+			Username: "  MixedCase ",
+			Email:    "Mixed.Case@Example.COM",
+			Password: "password123",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["email"] != "mixed.case@example.com" || response["username"] != "mixedcase" {
+			t.Errorf("Expected normalized identity, got email %v and username %v", response["email"], response["username"])
+		}
+	})
+
+	t.Run("Case variants of an existing email are rejected", func(t *testing.T) {
+		_, err := userService.CreateUser(context.Background(), requests.CreateUser{
+			Username: "someoneelse",
+			Email:    "TEST@example.com",
+			Password: "password123",
+		})
+		if err == nil {
+			t.Error("Expected an error for a case-insensitive duplicate email, got none")
+		}
+
+		err = testDB.Create(&models.User{ID: "raw-insert", Username: "rawinsert", Email: "Test@Example.com", Password: "x"}).Error
+		if err == nil {
+			t.Error("Expected the database to reject a case-insensitive duplicate email")
+		}
+	})
+
+	t.Run("Operators can create accounts with a role", func(t *testing.T) {
+		response, err := userService.CreateUserWithRole(context.Background(), requests.CreateUser{
+			Username: "boss",
+			Email:    "boss@example.com",
+			Password: "password123",
+		}, models.RoleAdmin)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		user, err := userService.FindUser(context.Background(), "BOSS@example.com")
+		if err != nil {
+			t.Fatalf("Expected to find the user by email, got: %v", err)
+		}
+		if user.ID != response["user_id"] || user.Role != models.RoleAdmin {
+			t.Errorf("Expected admin %v, got %+v", response["user_id"], user)
+		}
+
+		_, err = userService.CreateUserWithRole(context.Background(), requests.CreateUser{
+			Username: "root",
+			Email:    "root@example.com",
+			Password: "password123",
+		}, "superuser")
+		if !errors.Is(err, ErrInvalidRole) {
+			t.Errorf("Expected ErrInvalidRole, got: %v", err)
+		}
+		if _, err := userService.FindUser(context.Background(), "nobody"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("Expected ErrUserNotFound, got: %v", err)
+		}
+	})
 }
 
 func TestUserService_LoginUser(t *testing.T) {
 	testDB := utils.SetupTestDB()
 	defer utils.ClearTestDB(testDB)
 
-	originalDB := database.DB
-	database.DB = testDB
-	defer func() { database.DB = originalDB }()
+	store := repository.NewStore(testDB)
 
-	userService := &UserService{}
+	userService := &UserService{Store: store}
 
 	hashedPassword, _ := utils.HashPassword("correctpassword")
 	testUser := models.User{
@@ -78,11 +138,11 @@ func TestUserService_LoginUser(t *testing.T) {
 	testDB.Create(&testUser)
 
 	t.Run("Successful login", func(t *testing.T) {
-		loginReqSuccess := models.User{
+		loginReqSuccess := requests.Login{
 			Email:    "login@example.com",
 			Password: "correctpassword",
 		}
-		response, err := userService.LoginUser(loginReqSuccess)
+		response, err := userService.LoginUser(context.Background(), loginReqSuccess, testClient)
 		if err != nil {
 			t.Errorf("Expected no error, got: %v", err)
 		}
@@ -96,4 +156,324 @@ func TestUserService_LoginUser(t *testing.T) {
 			t.Errorf("Expected non-empty token, got: %v", response["token"])
 		}
 	})
+	t.Run("Login by username or mixed-case email", func(t *testing.T) {
+		for _, loginReq := range []requests.Login{
+			{Username: "LoginUser", Password: "correctpassword"},
+			{Identifier: "loginuser", Password: "correctpassword"},
+			{Identifier: " Login@Example.com ", Password: "correctpassword"},
+		} {
+			response, err := userService.LoginUser(context.Background(), loginReq, testClient)
+			if err != nil {
+				t.Errorf("Expected no error for %q, got: %v", loginReq.LoginIdentifier(), err)
+				continue
+			}
+			if token, ok := response["token"].(string); !ok || token == "" {
+				t.Errorf("Expected non-empty token for %q, got: %v", loginReq.LoginIdentifier(), response["token"])
+			}
+		}
+	})
+
+	t.Run("Wrong password and unknown email return the same error", func(t *testing.T) {
+		_, errWrongPassword := userService.LoginUser(context.Background(), requests.Login{Email: "login@example.com", Password: "wrong"}, testClient)
+		_, errUnknownEmail := userService.LoginUser(context.Background(), requests.Login{Email: "nobody@example.com", Password: "wrong"}, testClient)
+		if !errors.Is(errWrongPassword, ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials for wrong password, got: %v", errWrongPassword)
+		}
+		if !errors.Is(errUnknownEmail, ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials for unknown email, got: %v", errUnknownEmail)
+		}
+
+		var failures int64
+		testDB.Model(&models.AuditLog{}).Where("event = ?", AuditEventLoginFailed).Count(&failures)
+		if failures != 2 {
+			t.Errorf("Expected 2 failed login audit entries, got %d", failures)
+		}
+	})
+}
+
+func TestUserService_LoginUserLockout(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	userService := &UserService{
+		Store:      store,
+		LoginGuard: NewLoginGuard(config.LoginProtectionConfig{FreeAttempts: 1, MaxAccountFailures: 2, MaxIPFailures: 100}),
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := userService.LoginUser(context.Background(), requests.Login{Email: "locked@example.com", Password: "wrong"}, ClientInfo{IPAddress: "10.0.0.1"})
+		if !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("Attempt %d: expected ErrInvalidCredentials, got: %v", i+1, err)
+		}
+	}
+
+	_, err := userService.LoginUser(context.Background(), requests.Login{Email: "locked@example.com", Password: "wrong"}, ClientInfo{IPAddress: "10.0.0.1"})
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Expected LoginThrottledError after lockout, got: %v", err)
+	}
+	if throttled.RetryAfter <= 0 {
+		t.Errorf("Expected positive RetryAfter, got %v", throttled.RetryAfter)
+	}
+}
+
+func TestUserService_LoginUserLockoutIsPerAccount(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+	userService := &UserService{
+		Store:      store,
+		LoginGuard: NewLoginGuard(config.LoginProtectionConfig{FreeAttempts: 10, MaxAccountFailures: 2, MaxIPFailures: 100}),
+	}
+	hashedPassword, _ := utils.HashPassword("correctpassword")
+	testDB.Create(&models.User{ID: "user-lockout", Username: "lockout", Email: "lockout@example.com", Password: hashedPassword})
+	client := ClientInfo{IPAddress: "10.0.0.2"}
+
+	// Typing the account's ID matches no account and must not count
+	// against it.
+	for i := 0; i < 3; i++ {
+		userService.LoginUser(context.Background(), requests.Login{Identifier: "user-lockout", Password: "wrong"}, client)
+	}
+	if _, err := userService.LoginUser(context.Background(), requests.Login{Email: "lockout@example.com", Password: "correctpassword"}, client); err != nil {
+		t.Fatalf("Expected failures for an unknown identifier not to lock the account, got: %v", err)
+	}
+
+	// The email and the username share one budget.
+	if _, err := userService.LoginUser(context.Background(), requests.Login{Email: "lockout@example.com", Password: "wrong"}, client); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials, got: %v", err)
+	}
+	if _, err := userService.LoginUser(context.Background(), requests.Login{Username: "lockout", Password: "wrong"}, client); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("Expected ErrInvalidCredentials, got: %v", err)
+	}
+	_, err := userService.LoginUser(context.Background(), requests.Login{Email: "lockout@example.com", Password: "correctpassword"}, client)
+	var throttled *LoginThrottledError
+	if !errors.As(err, &throttled) {
+		t.Errorf("Expected the account to be locked after failures through its email and username, got: %v", err)
+	}
+}
+
+func TestUserService_LoginUserMFA(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	userService := &UserService{Store: store, MFAPolicy: config.MFAConfig{RequiredRoles: []string{models.RoleAdmin}}}
+
+	hashedPassword, _ := utils.HashPassword("correctpassword")
+	testDB.Create(&models.User{ID: "user-totp", Username: "totpuser", Email: "totp@example.com", Password: hashedPassword, Role: models.RoleUser, TOTPEnabled: true, TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"})
+	testDB.Create(&models.User{ID: "user-admin", Username: "adminuser", Email: "admin@example.com", Password: hashedPassword, Role: models.RoleAdmin})
+
+	t.Run("TOTP user gets an MFA challenge", func(t *testing.T) {
+		response, err := userService.LoginUser(context.Background(), requests.Login{Email: "totp@example.com", Password: "correctpassword"}, testClient)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["mfa_required"] != true {
+			t.Errorf("Expected mfa_required true, got: %v", response["mfa_required"])
+		}
+		if _, ok := response["token"]; ok {
+			t.Error("Expected no access token before MFA is completed")
+		}
+		mfaToken, _ := response["mfa_token"].(string)
+		if _, err := utils.VerifyMFAToken(mfaToken); err != nil {
+			t.Errorf("Expected a valid MFA token, got error: %v", err)
+		}
+	})
+
+	t.Run("Admin without MFA gets an enrollment-only token", func(t *testing.T) {
+		response, err := userService.LoginUser(context.Background(), requests.Login{Email: "admin@example.com", Password: "correctpassword"}, testClient)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response["mfa_enrollment_required"] != true {
+			t.Errorf("Expected mfa_enrollment_required true, got: %v", response["mfa_enrollment_required"])
+		}
+		claims, err := utils.ParseAccessToken(response["token"].(string))
+		if err != nil {
+			t.Fatalf("Expected a valid access token, got error: %v", err)
+		}
+		if !claims.MFAEnrollmentRequired {
+			t.Error("Expected token to carry the enrollment restriction")
+		}
+	})
+}
+
+func TestUserService_LoginUserRehash(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	defer utils.ConfigurePasswordHashing(utils.DefaultPasswordHashing())
+
+	userService := &UserService{Store: store}
+
+	bcryptHash, _ := utils.HashPassword("correctpassword")
+	testDB.Create(&models.User{ID: "user-rehash", Username: "rehashuser", Email: "rehash@example.com", Password: bcryptHash})
+
+	if err := utils.ConfigurePasswordHashing(config.PasswordHashingConfig{Algorithm: utils.AlgorithmArgon2id, Argon2MemoryKiB: 7168, Argon2Iterations: 1, Argon2Parallelism: 1}); err != nil {
+		t.Fatalf("ConfigurePasswordHashing failed: %v", err)
+	}
+
+	if _, err := userService.LoginUser(context.Background(), requests.Login{Email: "rehash@example.com", Password: "correctpassword"}, testClient); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var user models.User
+	testDB.Where("id = ?", "user-rehash").First(&user)
+	if !strings.HasPrefix(user.Password, "$argon2id$") {
+		t.Errorf("Expected password to be upgraded to argon2id, got %q", user.Password)
+	}
+	if !utils.CheckPasswordHash("correctpassword", user.Password) {
+		t.Error("Expected upgraded hash to verify the original password")
+	}
+}
+
+func TestUserService_PasswordPolicy(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	utils.ConfigurePasswordPolicy(config.PasswordPolicyConfig{MinLength: 10, RequireDigit: true, DisallowIdentity: true})
+	defer utils.ConfigurePasswordPolicy(config.PasswordPolicyConfig{})
+
+	userService := &UserService{Store: store}
+
+	_, err := userService.CreateUser(context.Background(), requests.CreateUser{Username: "weakling", Email: "weak@example.com", Password: "weakling"})
+	var policyErr *utils.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("Expected PasswordPolicyError, got: %v", err)
+	}
+	if len(policyErr.Violations) != 3 {
+		t.Errorf("Expected 3 violations, got %+v", policyErr.Violations)
+	}
+
+	var count int64
+	testDB.Model(&models.User{}).Where("username = ?", "weakling").Count(&count)
+	if count != 0 {
+		t.Error("Expected no user to be created for a weak password")
+	}
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	userService := &UserService{Store: store}
+
+	hashedPassword, _ := utils.HashPassword("OldPassword1")
+	testDB.Create(&models.User{ID: "user-change", Username: "changeuser", Email: "change@example.com", Password: hashedPassword})
+
+	t.Run("Wrong current password", func(t *testing.T) {
+		_, err := userService.ChangePassword(context.Background(), "user-change", requests.ChangePassword{CurrentPassword: "wrong", NewPassword: "NewPassword12"})
+		if !errors.Is(err, ErrIncorrectPassword) {
+			t.Errorf("Expected ErrIncorrectPassword, got: %v", err)
+		}
+	})
+
+	t.Run("Weak new password", func(t *testing.T) {
+		_, err := userService.ChangePassword(context.Background(), "user-change", requests.ChangePassword{CurrentPassword: "OldPassword1", NewPassword: "short"})
+		var policyErr *utils.PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			t.Errorf("Expected PasswordPolicyError, got: %v", err)
+		}
+	})
+
+	t.Run("Successful change", func(t *testing.T) {
+		if _, err := userService.ChangePassword(context.Background(), "user-change", requests.ChangePassword{CurrentPassword: "OldPassword1", NewPassword: "NewPassword12"}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var user models.User
+		testDB.Where("id = ?", "user-change").First(&user)
+		if !utils.CheckPasswordHash("NewPassword12", user.Password) {
+			t.Error("Expected the new password to be stored")
+		}
+	})
+}
+
+func TestUserService_PasswordReset(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	userService := &UserService{Store: store}
+
+	hashedPassword, _ := utils.HashPassword("OldPassword1")
+	testDB.Create(&models.User{ID: "user-reset", Username: "resetuser", Email: "reset@example.com", Password: hashedPassword})
+	session, err := userService.LoginUser(context.Background(), requests.Login{Email: "reset@example.com", Password: "OldPassword1"}, testClient)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	if _, err := userService.CreatePasswordReset(context.Background(), "admin-1", "missing-user"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got: %v", err)
+	}
+
+	response, err := userService.CreatePasswordReset(context.Background(), "admin-1", "user-reset")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	token, _ := response["reset_token"].(string)
+	if token == "" {
+		t.Fatal("Expected a reset token")
+	}
+
+	if _, err := userService.ResetPassword(context.Background(), requests.ResetPassword{Token: token, NewPassword: "short"}); err == nil {
+		t.Error("Expected weak password to be rejected")
+	}
+	if _, err := userService.ResetPassword(context.Background(), requests.ResetPassword{Token: token, NewPassword: "ResetPassword12"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := userService.ResetPassword(context.Background(), requests.ResetPassword{Token: token, NewPassword: "AnotherPassword12"}); !errors.Is(err, ErrInvalidResetToken) {
+		t.Errorf("Expected reused token to be rejected, got: %v", err)
+	}
+
+	var user models.User
+	testDB.Where("id = ?", "user-reset").First(&user)
+	if !utils.CheckPasswordHash("ResetPassword12", user.Password) {
+		t.Error("Expected the reset password to be stored")
+	}
+	if err := (&SessionService{Store: store}).VerifySession(context.Background(), session["session_id"].(string)); !errors.Is(err, ErrSessionTerminated) {
+		t.Errorf("Expected existing sessions to be terminated by the reset, got: %v", err)
+	}
+}
+
+func TestUserService_CreateScopedToken(t *testing.T) {
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+
+	store := repository.NewStore(testDB)
+
+	userService := &UserService{Store: store}
+	utils.CreateTestUserAndCart(testDB, "user-scoped", "scoped@example.com")
+	granted := utils.DefaultScopesForRole(models.RoleUser)
+
+	t.Run("Read-only token", func(t *testing.T) {
+		response, err := userService.CreateScopedToken(context.Background(), "user-scoped", "", granted, requests.CreateScopedToken{Scopes: []string{utils.ScopeMoviesRead}, ExpiresInMinutes: 15})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		claims, err := utils.ParseAccessToken(response["token"].(string))
+		if err != nil {
+			t.Fatalf("Expected a valid access token, got: %v", err)
+		}
+		if !utils.HasScope(claims.Scopes, utils.ScopeMoviesRead) || utils.HasScope(claims.Scopes, utils.ScopeCartWrite) {
+			t.Errorf("Expected a movies:read-only token, got %v", claims.Scopes)
+		}
+	})
+
+	t.Run("Cannot widen scopes", func(t *testing.T) {
+		_, err := userService.CreateScopedToken(context.Background(), "user-scoped", "", []string{utils.ScopeMoviesRead}, requests.CreateScopedToken{Scopes: []string{utils.ScopeCartWrite}})
+		if !errors.Is(err, ErrInvalidScope) {
+			t.Errorf("Expected ErrInvalidScope, got: %v", err)
+		}
+	})
 }
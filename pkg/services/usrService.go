@@ -1,98 +1,436 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"movierental/pkg/database"
+	"log/slog"
+	"movierental/config"
+	"movierental/pkg/metrics"
 	"movierental/pkg/models"
 	"movierental/pkg/models/requests"
+	"movierental/pkg/repository"
 	"movierental/pkg/utils"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
-type UserService struct{}
+const (
+	defaultPasswordResetTTL = time.Hour
+	defaultScopedTokenTTL   = time.Hour
 
-func (us *UserService) CreateUser(userReq requests.CreateUser) (map[string]interface{}, error) {
-	tx := database.DB.Begin()
-	if tx.Error != nil {
-		return nil, fmt.Errorf("failed to initiate transaction for user creation: %w", tx.Error)
-	}
+	AuditEventPasswordChanged       = "password_changed"
+	AuditEventPasswordResetIssued   = "password_reset_issued"
+	AuditEventPasswordResetComplete = "password_reset_completed"
+	AuditEventScopedTokenIssued     = "scoped_token_issued"
+)
 
-	newUserID := uuid.New().String()
-	newCartID := uuid.New().String()
+var (
+	ErrUserNotFound      = errors.New("user not found")
+	ErrIncorrectPassword = errors.New("current password is incorrect")
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	ErrInvalidRole       = errors.New("role must be one of user, staff or admin")
+	ErrInvalidUsername   = errors.New("username is required and must not contain '@'")
+	ErrInvalidEmail      = errors.New("email must be a valid email address")
+)
+
+type UserService struct {
+	Store            *repository.Store
+	LoginGuard       *LoginGuard
+	MFAPolicy        config.MFAConfig
+	PasswordResetTTL time.Duration
+	Sessions         *SessionService
+}
+
+func (us *UserService) CreateUser(ctx context.Context, userReq requests.CreateUser) (map[string]interface{}, error) {
+	return us.CreateUserWithRole(ctx, userReq, models.RoleUser)
+}
+
+// CreateUserWithRole creates an account with the given role under the same
+// rules as a signup. It is meant for operators; the API only ever creates
+// plain users.
+func (us *UserService) CreateUserWithRole(ctx context.Context, userReq requests.CreateUser, role string) (map[string]interface{}, error) {
+	if role != models.RoleUser && role != models.RoleStaff && role != models.RoleAdmin {
+		return nil, ErrInvalidRole
+	}
+	if err := validateIdentity(userReq.Username, userReq.Email); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidatePassword(userReq.Password, userReq.Username, userReq.Email); err != nil {
+		return nil, err
+	}
 
 	hashedPassword, err := utils.HashPassword(userReq.Password)
 	if err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to hash password for user '%s': %w", userReq.Username, err)
 	}
 
 	userEntity := models.User{
-		ID:       newUserID,
-		Username: userReq.Username,
-		Email:    userReq.Email,
+		ID:       uuid.New().String(),
+		Username: utils.NormalizeUsername(userReq.Username),
+		Email:    utils.NormalizeEmail(userReq.Email),
 		Password: hashedPassword,
+		Role:     role,
 	}
 
-	userResult := tx.Create(&userEntity)
-	if userResult.Error != nil {
-		tx.Rollback()
-		if errors.Is(userResult.Error, gorm.ErrDuplicatedKey) {
-			return nil, errors.New("username or email already exists. Please choose a different one")
-		}
-		return nil, fmt.Errorf("failed to create user '%s': %w", userReq.Username, userResult.Error)
-	}
-
-	cartEntity := requests.Cart{
-		Id:     newCartID,
-		UserId: newUserID,
-		Movies: []requests.CartMovieItem{},
-	}
-
-	cartResult := tx.Create(&cartEntity)
-	if cartResult.Error != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("failed to create cart for user '%s' (ID: %s): %w", userReq.Username, newUserID, cartResult.Error)
-	}
-
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to finalize user and cart creation: %w", err)
+	var cartEntity requests.Cart
+	err = us.Store.Transaction(ctx, func(tx *repository.Store) error {
+		var err error
+		cartEntity, err = createUserWithCart(ctx, tx, &userEntity)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	metrics.Signups.WithLabelValues("password").Inc()
 
 	return gin.H{
 		"message":  "User and cart created successfully!",
 		"user_id":  userEntity.ID,
 		"username": userEntity.Username,
 		"email":    userEntity.Email,
+		"role":     userEntity.Role,
 		"cart_id":  cartEntity.Id,
 	}, nil
 }
 
-func (us *UserService) LoginUser(loginReq models.User) (map[string]interface{}, error) {
-	var user models.User
-	err := database.DB.Where("email = ?", loginReq.Email).First(&user).Error
+// validateIdentity applies the signup rules for usernames and emails. A
+// username may not contain '@', or logins by email and by username could
+// match different accounts.
+func validateIdentity(username string, email string) error {
+	username = utils.NormalizeUsername(username)
+	if username == "" || strings.Contains(username, "@") {
+		return ErrInvalidUsername
+	}
+	email = utils.NormalizeEmail(email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+// FindUser looks a user up by ID, email or username.
+func (us *UserService) FindUser(ctx context.Context, identifier string) (models.User, error) {
+	user, err := us.Store.Users.FindByID(ctx, identifier)
+	if errors.Is(err, repository.ErrNotFound) {
+		user, err = us.Store.Users.FindByLogin(ctx, utils.NormalizeEmail(identifier))
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.User{}, ErrUserNotFound
+		}
+		return models.User{}, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	return user, nil
+}
+
+// createUserWithCart inserts user and its empty cart inside tx. Every way of
+// creating an account goes through here so each user always has a cart.
+func createUserWithCart(ctx context.Context, tx *repository.Store, user *models.User) (requests.Cart, error) {
+	if err := tx.Users.Create(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return requests.Cart{}, errors.New("username or email already exists. Please choose a different one")
+		}
+		return requests.Cart{}, fmt.Errorf("failed to create user '%s': %w", user.Username, err)
+	}
+
+	cart := requests.Cart{
+		Id:     uuid.New().String(),
+		UserId: user.ID,
+		Movies: []requests.CartMovieItem{},
+	}
+	if err := tx.Carts.Create(ctx, &cart); err != nil {
+		return requests.Cart{}, fmt.Errorf("failed to create cart for user '%s' (ID: %s): %w", user.Username, user.ID, err)
+	}
+	return cart, nil
+}
+
+func (us *UserService) LoginUser(ctx context.Context, loginReq requests.Login, client ClientInfo) (map[string]interface{}, error) {
+	clientIP := client.IPAddress
+	// Emails and usernames share the same normalization, so a single
+	// lower-cased identifier can be matched against either column.
+	identifier := utils.NormalizeEmail(loginReq.LoginIdentifier())
+	if identifier == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := us.Store.Users.FindByLogin(ctx, identifier)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("user '%s' not found. Please ensure the user exists", loginReq.Username)
+		if errors.Is(err, repository.ErrNotFound) {
+			if err := us.checkLoginGuard(ctx, unknownAccount(identifier), "", identifier, clientIP); err != nil {
+				return nil, err
+			}
+			// Burn the same amount of time as a real password check so response
+			// timing does not reveal whether the account exists.
+			utils.CheckPasswordHash(loginReq.Password, dummyPasswordHash())
+			us.registerLoginFailure(ctx, unknownAccount(identifier), "", identifier, clientIP, "unknown_account")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, fmt.Errorf("failed to retrieve user for login: %w", err)
 	}
+	// Known accounts are counted by ID, so their email and username share
+	// one budget.
+	if err := us.checkLoginGuard(ctx, userAccount(user.ID), user.ID, identifier, clientIP); err != nil {
+		return nil, err
+	}
 
 	passwordIsValid := utils.CheckPasswordHash(loginReq.Password, user.Password)
 	if !passwordIsValid {
-		return nil, errors.New("incorrect password")
+		us.registerLoginFailure(ctx, userAccount(user.ID), user.ID, identifier, clientIP, "wrong_password")
+		return nil, ErrInvalidCredentials
 	}
 
-	token, err := utils.GenerateToken(user.Email, user.ID)
+	if us.LoginGuard != nil {
+		us.LoginGuard.RecordSuccess(userAccount(user.ID))
+	}
+
+	if utils.PasswordNeedsRehash(user.Password) {
+		us.upgradePasswordHash(ctx, user.ID, loginReq.Password)
+	}
+
+	return loginResponse(ctx, user, us.MFAPolicy, sessionsOrDefault(us.Sessions, us.Store), client)
+}
+
+func (us *UserService) ChangePassword(ctx context.Context, userId string, changeReq requests.ChangePassword) (map[string]interface{}, error) {
+	user, err := us.Store.Users.FindByID(ctx, userId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token for login: %w", err)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user for password change: %w", err)
 	}
 
+	if !utils.CheckPasswordHash(changeReq.CurrentPassword, user.Password) {
+		return nil, ErrIncorrectPassword
+	}
+	if err := utils.ValidatePassword(changeReq.NewPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(changeReq.NewPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash new password: %w", err)
+	}
+	if err := us.Store.Users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return nil, fmt.Errorf("failed to update password: %w", err)
+	}
+	recordAudit(ctx, us.Store.AuditLogs, models.AuditLog{Event: AuditEventPasswordChanged, UserID: user.ID})
+
 	return gin.H{
-		"message": "Login successful!",
-		"token":   token,
+		"message": "Password changed successfully!",
 	}, nil
 }
+
+// CreatePasswordReset issues a one-time reset token for userId on behalf of
+// an administrator, who passes it on to the user out of band. Only the hash
+// of the token is stored.
+func (us *UserService) CreatePasswordReset(ctx context.Context, adminId string, userId string) (map[string]interface{}, error) {
+	user, err := us.Store.Users.FindByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user for password reset: %w", err)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	ttl := us.PasswordResetTTL
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+	resetToken := models.PasswordResetToken{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		CreatedBy: adminId,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := us.Store.PasswordResetTokens.Create(ctx, &resetToken); err != nil {
+		return nil, fmt.Errorf("failed to store password reset token: %w", err)
+	}
+	recordAudit(ctx, us.Store.AuditLogs, models.AuditLog{Event: AuditEventPasswordResetIssued, UserID: user.ID, Detail: "issued_by:" + adminId})
+
+	return gin.H{
+		"message":     "Password reset token created. Share it with the user through a trusted channel.",
+		"user_id":     user.ID,
+		"reset_token": token,
+		"expires_at":  resetToken.ExpiresAt,
+	}, nil
+}
+
+func (us *UserService) ResetPassword(ctx context.Context, resetReq requests.ResetPassword) (map[string]interface{}, error) {
+	resetToken, err := us.Store.PasswordResetTokens.FindValid(ctx, utils.HashToken(resetReq.Token), time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrInvalidResetToken
+		}
+		return nil, fmt.Errorf("failed to retrieve password reset token: %w", err)
+	}
+
+	user, err := us.Store.Users.FindByID(ctx, resetToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user for password reset: %w", err)
+	}
+	if err := utils.ValidatePassword(resetReq.NewPassword, user.Username, user.Email); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := utils.HashPassword(resetReq.NewPassword)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash new password: %w", err)
+	}
+
+	err = us.Store.Transaction(ctx, func(tx *repository.Store) error {
+		now := time.Now()
+		marked, err := tx.PasswordResetTokens.MarkUsed(ctx, resetToken.ID, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInvalidResetToken
+		}
+		if err := tx.Users.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+			return err
+		}
+		// Whoever knew the old password must not stay signed in.
+		return tx.Sessions.RevokeAllForUser(ctx, user.ID, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidResetToken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to reset password: %w", err)
+	}
+	recordAudit(ctx, us.Store.AuditLogs, models.AuditLog{Event: AuditEventPasswordResetComplete, UserID: user.ID})
+
+	return gin.H{
+		"message": "Password reset successfully!",
+	}, nil
+}
+
+// CreateScopedToken issues a short-lived access token restricted to the
+// requested scopes, e.g. a read-only token for a dashboard. The scopes must be
+// covered by grantedScopes, the scopes of the token making the request, and
+// the new token belongs to the same session.
+func (us *UserService) CreateScopedToken(ctx context.Context, userId string, sessionId string, grantedScopes []string, tokenReq requests.CreateScopedToken) (map[string]interface{}, error) {
+	if err := utils.ValidateScopes(tokenReq.Scopes, grantedScopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidScope, err)
+	}
+
+	user, err := us.Store.Users.FindByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve user for scoped token: %w", err)
+	}
+
+	ttl := defaultScopedTokenTTL
+	if tokenReq.ExpiresInMinutes > 0 {
+		ttl = time.Duration(tokenReq.ExpiresInMinutes) * time.Minute
+	}
+	token, err := utils.GenerateAccessToken(utils.TokenClaims{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Role:      user.Role,
+		Scopes:    tokenReq.Scopes,
+		SessionID: sessionId,
+		TTL:       ttl,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	recordAudit(ctx, us.Store.AuditLogs, models.AuditLog{Event: AuditEventScopedTokenIssued, UserID: user.ID, Detail: strings.Join(tokenReq.Scopes, " ")})
+
+	return gin.H{
+		"message":    "Token created",
+		"token":      token,
+		"scopes":     tokenReq.Scopes,
+		"expires_at": time.Now().Add(ttl),
+	}, nil
+}
+
+// loginResponse finishes any successful primary authentication: users with
+// TOTP get an MFA challenge, users whose role requires MFA but who have not
+// enrolled get an enrollment-only token, everyone else gets an access token.
+// Tokens start a new session and come with its refresh token.
+func loginResponse(ctx context.Context, user models.User, mfaPolicy config.MFAConfig, sessions *SessionService, client ClientInfo) (map[string]interface{}, error) {
+	if user.TOTPEnabled {
+		mfaToken, err := utils.GenerateMFAToken(user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate MFA challenge token: %w", err)
+		}
+		return gin.H{
+			"message":      "MFA verification required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+		}, nil
+	}
+
+	enrollmentRequired := MFARequiredForRole(mfaPolicy.RequiredRoles, user.Role)
+	response, err := sessions.issueSessionTokens(ctx, user, enrollmentRequired, client)
+	if err != nil {
+		return nil, err
+	}
+
+	if enrollmentRequired {
+		response["message"] = "Login successful! MFA enrollment is required for your role before accessing other endpoints."
+		response["mfa_enrollment_required"] = true
+		return response, nil
+	}
+
+	response["message"] = "Login successful!"
+	return response, nil
+}
+
+// upgradePasswordHash re-hashes the password with the current algorithm and
+// cost. It runs only after a successful login, the one moment the plaintext
+// is available, and never fails the login itself.
+func (us *UserService) upgradePasswordHash(ctx context.Context, userID string, password string) {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		slog.WarnContext(ctx, "Failed to rehash password", "user_id", userID, "error", err)
+		return
+	}
+	if err := us.Store.Users.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		slog.WarnContext(ctx, "Failed to store upgraded password hash", "user_id", userID, "error", err)
+	}
+}
+
+func (us *UserService) checkLoginGuard(ctx context.Context, account string, userID string, identifier string, clientIP string) error {
+	if us.LoginGuard == nil {
+		return nil
+	}
+	if wait := us.LoginGuard.Check(account, clientIP); wait > 0 {
+		recordFailedLogin(ctx, us.Store.AuditLogs, userID, identifier, clientIP, "throttled")
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (us *UserService) registerLoginFailure(ctx context.Context, account string, userID string, identifier string, clientIP string, reason string) {
+	if us.LoginGuard != nil {
+		us.LoginGuard.RecordFailure(account, clientIP)
+	}
+	recordFailedLogin(ctx, us.Store.AuditLogs, userID, identifier, clientIP, reason)
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = utils.HashPassword(uuid.New().String())
+	})
+	return dummyHash
+}
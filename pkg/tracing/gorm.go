@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const spanKey = "tracing:span"
+
+// InstrumentDB records a client span for every statement GORM issues on db,
+// as a child of the span in the statement's context.
+func InstrumentDB(db *gorm.DB) error {
+	tracer := otel.Tracer(instrumentationName)
+	callbacks := db.Callback()
+	registrations := []struct {
+		operation string
+		before    error
+		after     error
+	}{
+		{"create",
+			callbacks.Create().Before("gorm:create").Register("tracing:before_create", startSpan(tracer, "create")),
+			callbacks.Create().After("gorm:create").Register("tracing:after_create", endSpan)},
+		{"query",
+			callbacks.Query().Before("gorm:query").Register("tracing:before_query", startSpan(tracer, "query")),
+			callbacks.Query().After("gorm:query").Register("tracing:after_query", endSpan)},
+		{"update",
+			callbacks.Update().Before("gorm:update").Register("tracing:before_update", startSpan(tracer, "update")),
+			callbacks.Update().After("gorm:update").Register("tracing:after_update", endSpan)},
+		{"delete",
+			callbacks.Delete().Before("gorm:delete").Register("tracing:before_delete", startSpan(tracer, "delete")),
+			callbacks.Delete().After("gorm:delete").Register("tracing:after_delete", endSpan)},
+		{"row",
+			callbacks.Row().Before("gorm:row").Register("tracing:before_row", startSpan(tracer, "row")),
+			callbacks.Row().After("gorm:row").Register("tracing:after_row", endSpan)},
+		{"raw",
+			callbacks.Raw().Before("gorm:raw").Register("tracing:before_raw", startSpan(tracer, "raw")),
+			callbacks.Raw().After("gorm:raw").Register("tracing:after_raw", endSpan)},
+	}
+	for _, r := range registrations {
+		if err := errors.Join(r.before, r.after); err != nil {
+			return fmt.Errorf("failed to register %s tracing callbacks: %w", r.operation, err)
+		}
+	}
+	return nil
+}
+
+func startSpan(tracer trace.Tracer, operation string) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		spanName := "gorm." + operation
+		if tx.Statement.Table != "" {
+			spanName += " " + tx.Statement.Table
+		}
+		ctx, span := tracer.Start(tx.Statement.Context, spanName,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(tx.Dialector.Name()),
+				semconv.DBOperationName(operation),
+			),
+		)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	value, ok := tx.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+	defer span.End()
+
+	attributes := []attribute.KeyValue{semconv.DBQueryText(tx.Statement.SQL.String())}
+	if tx.Statement.Table != "" {
+		attributes = append(attributes, semconv.DBCollectionName(tx.Statement.Table))
+	}
+	span.SetAttributes(attributes...)
+	if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(tx.Error)
+		span.SetStatus(codes.Error, tx.Error.Error())
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"movierental/config"
+	"movierental/pkg/models"
+	"movierental/pkg/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	if _, err := Setup(context.Background(), config.TracingConfig{}); err != nil {
+		t.Fatalf("Failed to set up tracing: %v", err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestMiddleware_TracesHandlerAndQueries(t *testing.T) {
+	recorder := useRecorder(t)
+	testDB := utils.SetupTestDB()
+	defer utils.ClearTestDB(testDB)
+	if err := InstrumentDB(testDB); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/users/:id", func(c *gin.Context) {
+		var user models.User
+		testDB.WithContext(c.Request.Context()).Where("id = ?", c.Param("id")).Find(&user)
+		c.Status(http.StatusOK)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a query span and a request span, got %d spans", len(spans))
+	}
+	query, request := spans[0], spans[1]
+	if request.Name() != "GET /users/:id" {
+		t.Errorf("Expected the request span to be named after the route, got %q", request.Name())
+	}
+	if request.SpanContext().TraceID().String() != traceID {
+		t.Errorf("Expected the incoming trace to be continued, got trace %s", request.SpanContext().TraceID())
+	}
+	if query.Name() != "gorm.query users" {
+		t.Errorf("Expected a query span for users, got %q", query.Name())
+	}
+	if query.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Error("Expected the query span to be a child of the request span")
+	}
+}
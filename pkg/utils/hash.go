@@ -1,13 +1,204 @@
 package utils
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"movierental/config"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+
+	// Bounds for argon2id parameters, matching the configuration checks.
+	argon2MinMemoryKiB   = 7168
+	argon2MaxMemoryKiB   = 4194304
+	argon2MaxIterations  = 100
+	argon2MaxParallelism = 64
+	// Stored hashes with a shorter salt or key are refused; an empty key
+	// would match any password.
+	argon2MinSaltLength = 8
+	argon2MinKeyLength  = 16
+)
+
+var passwordHashing atomic.Pointer[config.PasswordHashingConfig]
+
+func init() {
+	params := DefaultPasswordHashing()
+	passwordHashing.Store(&params)
+}
+
+func DefaultPasswordHashing() config.PasswordHashingConfig {
+	return config.PasswordHashingConfig{
+		Algorithm:         AlgorithmBcrypt,
+		BcryptCost:        bcrypt.DefaultCost,
+		Argon2MemoryKiB:   19 * 1024,
+		Argon2Iterations:  2,
+		Argon2Parallelism: 1,
+	}
+}
+
+// ConfigurePasswordHashing sets the algorithm and cost used for new hashes.
+// Zero values fall back to DefaultPasswordHashing. Existing hashes keep
+// verifying because each hash records the algorithm and parameters it used.
+func ConfigurePasswordHashing(cfg config.PasswordHashingConfig) error {
+	defaults := DefaultPasswordHashing()
+	if cfg.Algorithm == "" {
+		cfg.Algorithm = defaults.Algorithm
+	}
+	if cfg.BcryptCost == 0 {
+		cfg.BcryptCost = defaults.BcryptCost
+	}
+	if cfg.Argon2MemoryKiB == 0 {
+		cfg.Argon2MemoryKiB = defaults.Argon2MemoryKiB
+	}
+	if cfg.Argon2Iterations == 0 {
+		cfg.Argon2Iterations = defaults.Argon2Iterations
+	}
+	if cfg.Argon2Parallelism == 0 {
+		cfg.Argon2Parallelism = defaults.Argon2Parallelism
+	}
+
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		if err := checkArgon2Params(cfg.Argon2MemoryKiB, cfg.Argon2Iterations, cfg.Argon2Parallelism); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported password hashing algorithm %q", cfg.Algorithm)
+	}
+
+	passwordHashing.Store(&cfg)
+	return nil
+}
 
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	params := passwordHashing.Load()
+	if params.Algorithm == AlgorithmArgon2id {
+		return hashArgon2id(password, params)
+	}
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), params.BcryptCost)
 	return string(bytes), err
 }
 
 func CheckPasswordHash(password string, hashedPassword string) bool {
+	if strings.HasPrefix(hashedPassword, "$"+AlgorithmArgon2id+"$") {
+		return checkArgon2id(password, hashedPassword)
+	}
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 	return err == nil
 }
+
+// PasswordNeedsRehash reports whether hashedPassword was produced with a
+// different algorithm or cost than the current configuration.
+func PasswordNeedsRehash(hashedPassword string) bool {
+	params := passwordHashing.Load()
+
+	if strings.HasPrefix(hashedPassword, "$"+AlgorithmArgon2id+"$") {
+		if params.Algorithm != AlgorithmArgon2id {
+			return true
+		}
+		memory, iterations, parallelism, _, _, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			return true
+		}
+		return memory != params.Argon2MemoryKiB || iterations != params.Argon2Iterations || parallelism != params.Argon2Parallelism
+	}
+
+	if params.Algorithm != AlgorithmBcrypt {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != params.BcryptCost
+}
+
+// hashArgon2id encodes the hash in the PHC string format used by the
+// reference implementation: $argon2id$v=19$m=<KiB>,t=<iterations>,p=<lanes>$<salt>$<key>.
+func hashArgon2id(password string, params *config.PasswordHashingConfig) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, params.Argon2Iterations, params.Argon2MemoryKiB, params.Argon2Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		AlgorithmArgon2id,
+		argon2.Version,
+		params.Argon2MemoryKiB,
+		params.Argon2Iterations,
+		params.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func checkArgon2id(password string, hashedPassword string) bool {
+	memory, iterations, parallelism, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return false
+	}
+	candidate := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+func decodeArgon2id(hashedPassword string) (memory uint32, iterations uint32, parallelism uint8, salt []byte, key []byte, err error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return 0, 0, 0, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return 0, 0, 0, nil, nil, fmt.Errorf("malformed argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return 0, 0, 0, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return 0, 0, 0, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+	if err = checkArgon2Params(memory, iterations, parallelism); err != nil {
+		return 0, 0, 0, nil, nil, err
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil || len(salt) < argon2MinSaltLength {
+		return 0, 0, 0, nil, nil, fmt.Errorf("malformed argon2id salt")
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(key) < argon2MinKeyLength {
+		return 0, 0, 0, nil, nil, fmt.Errorf("malformed argon2id key")
+	}
+	return memory, iterations, parallelism, salt, key, nil
+}
+
+func checkArgon2Params(memory uint32, iterations uint32, parallelism uint8) error {
+	switch {
+	case memory < argon2MinMemoryKiB || memory > argon2MaxMemoryKiB:
+		return fmt.Errorf("argon2id memory must be between %d and %d KiB, got %d", argon2MinMemoryKiB, argon2MaxMemoryKiB, memory)
+	case iterations < 1 || iterations > argon2MaxIterations:
+		return fmt.Errorf("argon2id iterations must be between 1 and %d, got %d", argon2MaxIterations, iterations)
+	case parallelism < 1 || parallelism > argon2MaxParallelism:
+		return fmt.Errorf("argon2id parallelism must be between 1 and %d, got %d", argon2MaxParallelism, parallelism)
+	}
+	return nil
+}
+
+// HashToken returns the hex SHA-256 digest of a high-entropy secret such as a
+// recovery code. Unlike passwords these need no slow, salted hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"movierental/config"
+	"strings"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -68,3 +72,85 @@ func TestCheckPasswordHash(t *testing.T) {
 		})
 	}
 }
+
+func withPasswordHashing(t *testing.T, cfg config.PasswordHashingConfig) {
+	t.Helper()
+	previous := *passwordHashing.Load()
+	if err := ConfigurePasswordHashing(cfg); err != nil {
+		t.Fatalf("ConfigurePasswordHashing failed: %v", err)
+	}
+	t.Cleanup(func() { passwordHashing.Store(&previous) })
+}
+
+func TestArgon2idHashPassword(t *testing.T) {
+	withPasswordHashing(t, config.PasswordHashingConfig{Algorithm: AlgorithmArgon2id, Argon2MemoryKiB: 7168, Argon2Iterations: 1, Argon2Parallelism: 1})
+
+	hashedPassword, err := HashPassword("mySecretPassword123")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=7168,t=1,p=1$") {
+		t.Errorf("Expected PHC-encoded argon2id hash, got %q", hashedPassword)
+	}
+	if !CheckPasswordHash("mySecretPassword123", hashedPassword) {
+		t.Error("CheckPasswordHash failed to verify a newly hashed argon2id password")
+	}
+	if CheckPasswordHash("wrongPassword", hashedPassword) {
+		t.Error("CheckPasswordHash accepted a wrong password")
+	}
+	if PasswordNeedsRehash(hashedPassword) {
+		t.Error("Expected a hash with current parameters not to need rehashing")
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	withPasswordHashing(t, config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	bcryptHash, _ := HashPassword("password")
+	if PasswordNeedsRehash(bcryptHash) {
+		t.Error("Expected bcrypt hash with current cost not to need rehashing")
+	}
+
+	withPasswordHashing(t, config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	if !PasswordNeedsRehash(bcryptHash) {
+		t.Error("Expected bcrypt hash to need rehashing after the cost changed")
+	}
+
+	withPasswordHashing(t, config.PasswordHashingConfig{Algorithm: AlgorithmArgon2id, Argon2MemoryKiB: 7168, Argon2Iterations: 1, Argon2Parallelism: 1})
+	if !PasswordNeedsRehash(bcryptHash) {
+		t.Error("Expected bcrypt hash to need rehashing after switching to argon2id")
+	}
+	if !CheckPasswordHash("password", bcryptHash) {
+		t.Error("Expected bcrypt hash to keep verifying after switching to argon2id")
+	}
+}
+
+func TestConfigurePasswordHashingRejectsUnknownAlgorithm(t *testing.T) {
+	if err := ConfigurePasswordHashing(config.PasswordHashingConfig{Algorithm: "md5"}); err == nil {
+		t.Error("Expected an error for an unsupported algorithm")
+	}
+}
+
+func TestConfigurePasswordHashingRejectsWeakArgon2id(t *testing.T) {
+	for _, cfg := range []config.PasswordHashingConfig{
+		{Algorithm: AlgorithmArgon2id, Argon2MemoryKiB: 64},
+		{Algorithm: AlgorithmArgon2id, Argon2Iterations: 1000},
+		{Algorithm: AlgorithmArgon2id, Argon2Parallelism: 200},
+	} {
+		if err := ConfigurePasswordHashing(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestCheckPasswordHashRejectsDegenerateArgon2id(t *testing.T) {
+	salt := "c2FsdHNhbHRzYWx0c2FsdA"
+	for _, hash := range []string{
+		"$argon2id$v=19$m=7168,t=1,p=1$" + salt + "$",
+		"$argon2id$v=19$m=7168,t=1,p=0$" + salt + "$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=7168,t=1,p=1$c2FsdA$a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5",
+	} {
+		if CheckPasswordHash("", hash) || CheckPasswordHash("password", hash) {
+			t.Errorf("Expected %q to match no password", hash)
+		}
+	}
+}
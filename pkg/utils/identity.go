@@ -0,0 +1,13 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail lower-cases and trims an email so that addresses differing
+// only in case map to the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func NormalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
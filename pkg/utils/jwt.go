@@ -2,48 +2,238 @@ package utils
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-const secretKey = "secret"
+// MinSigningKeyLength is the shortest accepted HMAC key, matching the
+// SHA-256 output size.
+const MinSigningKeyLength = 32
 
-func GenerateToken(email string, userId string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"email":  email,
-		"userId": userId,
-		"exp":    time.Now().Add(time.Hour).Unix(),
+// ErrSigningKeyNotConfigured is returned when tokens are issued or checked
+// before ConfigureSigningKey has been called.
+var ErrSigningKeyNotConfigured = errors.New("token signing key is not configured")
+
+var signingKey []byte
+
+// ConfigureSigningKey sets the key that signs and verifies every token. It
+// must be called once at startup, before any token is issued.
+func ConfigureSigningKey(secret string) error {
+	if len(secret) < MinSigningKeyLength {
+		return fmt.Errorf("token signing key must be at least %d bytes, got %d", MinSigningKeyLength, len(secret))
+	}
+	signingKey = []byte(secret)
+	return nil
+}
+
+func sign(claims jwt.MapClaims) (string, error) {
+	if len(signingKey) == 0 {
+		return "", ErrSigningKeyNotConfigured
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signingKey)
+}
+
+const (
+	accessTokenTTL        = time.Hour
+	mfaTokenTTL           = 5 * time.Minute
+	mfaTokenPurpose       = "mfa"
+	oidcStateTokenTTL     = 10 * time.Minute
+	oidcStateTokenPurpose = "oidc_state"
+)
+
+type TokenClaims struct {
+	UserID string
+	Email  string
+	Role   string
+	// MFAEnrollmentRequired marks a token issued to a user whose role requires
+	// MFA but who has not enrolled yet. It only grants access to enrollment.
+	MFAEnrollmentRequired bool
+	// Scopes limits what the token may be used for. A token without a scope
+	// claim grants none.
+	Scopes []string
+	// SessionID ties the token to a login session so that terminating the
+	// session also rejects its access tokens.
+	SessionID string
+	// TTL overrides the default access token lifetime when set.
+	TTL time.Duration
+}
+
+func GenerateAccessToken(claims TokenClaims) (string, error) {
+	ttl := claims.TTL
+	if ttl <= 0 {
+		ttl = accessTokenTTL
+	}
+	mapClaims := jwt.MapClaims{
+		"email":  claims.Email,
+		"userId": claims.UserID,
+		"exp":    time.Now().Add(ttl).Unix(),
+	}
+	if claims.Role != "" {
+		mapClaims["role"] = claims.Role
+	}
+	if claims.MFAEnrollmentRequired {
+		mapClaims["mfa_enrollment_required"] = true
+	}
+	if len(claims.Scopes) > 0 {
+		mapClaims["scope"] = strings.Join(claims.Scopes, " ")
+	}
+	if claims.SessionID != "" {
+		mapClaims["sid"] = claims.SessionID
+	}
+	return sign(mapClaims)
+}
+
+// MFAChallenge is what an MFA token carries. ID tells tokens apart so each
+// can be redeemed only once.
+type MFAChallenge struct {
+	ID        string
+	UserID    string
+	ExpiresAt time.Time
+}
+
+// GenerateMFAToken issues a short-lived token proving the password step of a
+// two-step login succeeded. It is not accepted as an access token.
+func GenerateMFAToken(userId string) (string, error) {
+	return sign(jwt.MapClaims{
+		"jti":     uuid.New().String(),
+		"userId":  userId,
+		"purpose": mfaTokenPurpose,
+		"exp":     time.Now().Add(mfaTokenTTL).Unix(),
 	})
-	return token.SignedString([]byte(secretKey))
 }
 
 func VerifyToken(tokenString string) (string, error) {
+	claims, err := ParseAccessToken(tokenString)
+	if err != nil {
+		return "", err
+	}
+	return claims.UserID, nil
+}
+
+func ParseAccessToken(tokenString string) (TokenClaims, error) {
+	claims, err := parseSignedToken(tokenString)
+	if err != nil {
+		return TokenClaims{}, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != "" {
+		return TokenClaims{}, errors.New("invalid token")
+	}
+
+	userId, _ := claims["userId"].(string)
+	if userId == "" {
+		return TokenClaims{}, errors.New("invalid token claims")
+	}
+	email, _ := claims["email"].(string)
+	role, _ := claims["role"].(string)
+	enrollmentRequired, _ := claims["mfa_enrollment_required"].(bool)
+	sessionId, _ := claims["sid"].(string)
+	scope, _ := claims["scope"].(string)
+
+	return TokenClaims{
+		UserID:                userId,
+		Email:                 email,
+		Role:                  role,
+		MFAEnrollmentRequired: enrollmentRequired,
+		Scopes:                strings.Fields(scope),
+		SessionID:             sessionId,
+	}, nil
+}
+
+func VerifyMFAToken(tokenString string) (string, error) {
+	challenge, err := ParseMFAToken(tokenString)
+	return challenge.UserID, err
+}
+
+func ParseMFAToken(tokenString string) (MFAChallenge, error) {
+	claims, err := parseSignedToken(tokenString)
+	if err != nil {
+		return MFAChallenge{}, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != mfaTokenPurpose {
+		return MFAChallenge{}, errors.New("invalid MFA token")
+	}
+	var challenge MFAChallenge
+	challenge.ID, _ = claims["jti"].(string)
+	challenge.UserID, _ = claims["userId"].(string)
+	if challenge.ID == "" || challenge.UserID == "" {
+		return MFAChallenge{}, errors.New("invalid token claims")
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return MFAChallenge{}, errors.New("invalid token claims")
+	}
+	challenge.ExpiresAt = expiresAt.Time
+	return challenge, nil
+}
+
+// OIDCLoginState is the per-login data that must survive the round trip to
+// an external identity provider.
+type OIDCLoginState struct {
+	Provider     string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+// GenerateOIDCStateToken signs the login state so it can be kept in a cookie
+// instead of server-side storage.
+func GenerateOIDCStateToken(state OIDCLoginState) (string, error) {
+	return sign(jwt.MapClaims{
+		"purpose":       oidcStateTokenPurpose,
+		"provider":      state.Provider,
+		"state":         state.State,
+		"nonce":         state.Nonce,
+		"code_verifier": state.CodeVerifier,
+		"exp":           time.Now().Add(oidcStateTokenTTL).Unix(),
+	})
+}
+
+func VerifyOIDCStateToken(tokenString string) (OIDCLoginState, error) {
+	claims, err := parseSignedToken(tokenString)
+	if err != nil {
+		return OIDCLoginState{}, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != oidcStateTokenPurpose {
+		return OIDCLoginState{}, errors.New("invalid OIDC state token")
+	}
+	var state OIDCLoginState
+	state.Provider, _ = claims["provider"].(string)
+	state.State, _ = claims["state"].(string)
+	state.Nonce, _ = claims["nonce"].(string)
+	state.CodeVerifier, _ = claims["code_verifier"].(string)
+	if state.State == "" || state.CodeVerifier == "" {
+		return OIDCLoginState{}, errors.New("invalid OIDC state token")
+	}
+	return state, nil
+}
+
+func parseSignedToken(tokenString string) (jwt.MapClaims, error) {
 	if tokenString == "" {
-		return "", errors.New("empty token")
+		return nil, errors.New("empty token")
+	}
+	if len(signingKey) == 0 {
+		return nil, ErrSigningKeyNotConfigured
 	}
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return []byte(secretKey), nil
+		return signingKey, nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if !token.Valid {
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", errors.New("invalid token claims")
+		return nil, errors.New("invalid token claims")
 	}
-
-	userId := claims["userId"].(string)
-	if userId == "" {
-		return "", errors.New("invalid token claims")
-	}
-
-	return userId, nil
+	return claims, nil
 }
@@ -1,19 +1,24 @@
 package utils
 
 import (
+	"errors"
+	"os"
 	"testing"
 )
 
-const testSecretKey = "secret"
+func TestMain(m *testing.M) {
+	ConfigureTestSigningKey()
+	os.Exit(m.Run())
+}
 
-func TestGenerateToken(t *testing.T) {
+func TestGenerateAccessToken(t *testing.T) {
 	email := "test@example.com"
 	userID := "user123"
 
-	tokenString, err := GenerateToken(email, userID)
+	tokenString, err := GenerateAccessToken(TokenClaims{UserID: userID, Email: email, Role: "user", SessionID: "session-1"})
 
 	if err != nil {
-		t.Fatalf("GenerateToken failed: %v", err)
+		t.Fatalf("GenerateAccessToken failed: %v", err)
 	}
 	if tokenString == "" {
 		t.Error("Generated token string is empty")
@@ -28,3 +33,75 @@ func TestGenerateToken(t *testing.T) {
 		t.Errorf("Expected userID %q, got %q", userID, gotUserID)
 	}
 }
+
+func TestMFATokenIsNotAnAccessToken(t *testing.T) {
+	mfaToken, err := GenerateMFAToken("user123")
+	if err != nil {
+		t.Fatalf("GenerateMFAToken failed: %v", err)
+	}
+
+	if _, err := VerifyToken(mfaToken); err == nil {
+		t.Error("Expected MFA token to be rejected as an access token")
+	}
+
+	gotUserID, err := VerifyMFAToken(mfaToken)
+	if err != nil {
+		t.Fatalf("VerifyMFAToken failed: %v", err)
+	}
+	if gotUserID != "user123" {
+		t.Errorf("Expected userID %q, got %q", "user123", gotUserID)
+	}
+
+	challenge, err := ParseMFAToken(mfaToken)
+	if err != nil {
+		t.Fatalf("ParseMFAToken failed: %v", err)
+	}
+	other, _ := GenerateMFAToken("user123")
+	if otherChallenge, _ := ParseMFAToken(other); challenge.ID == "" || challenge.ID == otherChallenge.ID {
+		t.Errorf("Expected every MFA token to have its own ID, got %q and %q", challenge.ID, otherChallenge.ID)
+	}
+	if challenge.ExpiresAt.IsZero() {
+		t.Error("Expected the MFA token expiry to be set")
+	}
+
+	accessToken, _ := GenerateAccessToken(TokenClaims{UserID: "user123", Email: "test@example.com"})
+	if _, err := VerifyMFAToken(accessToken); err == nil {
+		t.Error("Expected access token to be rejected as an MFA token")
+	}
+}
+
+func TestParseAccessTokenEnrollmentFlag(t *testing.T) {
+	token, err := GenerateAccessToken(TokenClaims{UserID: "user123", Email: "test@example.com", MFAEnrollmentRequired: true})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+
+	claims, err := ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if !claims.MFAEnrollmentRequired {
+		t.Error("Expected MFAEnrollmentRequired to be set")
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	defer ConfigureTestSigningKey()
+
+	if err := ConfigureSigningKey("secret"); err == nil {
+		t.Error("Expected a short signing key to be rejected")
+	}
+
+	token, _ := GenerateAccessToken(TokenClaims{UserID: "user123"})
+	if err := ConfigureSigningKey("another-signing-key-of-sufficient-length"); err != nil {
+		t.Fatalf("ConfigureSigningKey failed: %v", err)
+	}
+	if _, err := ParseAccessToken(token); err == nil {
+		t.Error("Expected a token signed with another key to be rejected")
+	}
+
+	signingKey = nil
+	if _, err := GenerateAccessToken(TokenClaims{UserID: "user123"}); !errors.Is(err, ErrSigningKeyNotConfigured) {
+		t.Errorf("Expected ErrSigningKeyNotConfigured, got: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	_ "embed"
+	"fmt"
+	"movierental/config"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// maxPasswordBytes caps what is hashed at all; bcrypt accepts no more
+	// than bcryptMaxPasswordBytes.
+	maxPasswordBytes       = 128
+	bcryptMaxPasswordBytes = 72
+)
+
+//go:embed commonPasswords.txt
+var commonPasswordsFile string
+
+var (
+	commonPasswordsOnce sync.Once
+	commonPasswords     map[string]struct{}
+)
+
+var passwordPolicy atomic.Pointer[config.PasswordPolicyConfig]
+
+func init() {
+	ConfigurePasswordPolicy(config.PasswordPolicyConfig{})
+}
+
+type PasswordRuleViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError lists every rule a password failed, so clients can
+// show all problems at once instead of one per attempt.
+type PasswordPolicyError struct {
+	Violations []PasswordRuleViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password does not meet the password policy: " + strings.Join(messages, "; ")
+}
+
+func ConfigurePasswordPolicy(cfg config.PasswordPolicyConfig) {
+	if cfg.MinLength <= 0 {
+		cfg.MinLength = defaultPasswordMinLength
+	}
+	passwordPolicy.Store(&cfg)
+}
+
+// ValidatePassword checks password against the configured policy. username
+// and email are used to reject passwords built from the account's identity.
+// It returns a *PasswordPolicyError when any rule fails.
+func ValidatePassword(password string, username string, email string) error {
+	policy := passwordPolicy.Load()
+	var violations []PasswordRuleViolation
+
+	if len([]rune(password)) < policy.MinLength {
+		violations = append(violations, PasswordRuleViolation{
+			Rule:    "min_length",
+			Message: fmt.Sprintf("must be at least %d characters long", policy.MinLength),
+		})
+	}
+	if maxBytes := maxPasswordLength(); len(password) > maxBytes {
+		violations = append(violations, PasswordRuleViolation{
+			Rule:    "max_length",
+			Message: fmt.Sprintf("must be at most %d bytes long; accented letters and emoji take more than one", maxBytes),
+		})
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if policy.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordRuleViolation{Rule: "require_upper", Message: "must contain an uppercase letter"})
+	}
+	if policy.RequireLower && !hasLower {
+		violations = append(violations, PasswordRuleViolation{Rule: "require_lower", Message: "must contain a lowercase letter"})
+	}
+	if policy.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordRuleViolation{Rule: "require_digit", Message: "must contain a digit"})
+	}
+	if policy.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordRuleViolation{Rule: "require_symbol", Message: "must contain a symbol"})
+	}
+
+	lowered := strings.ToLower(password)
+	if policy.DisallowIdentity && containsIdentity(lowered, username, email) {
+		violations = append(violations, PasswordRuleViolation{Rule: "disallow_identity", Message: "must not contain your username or email"})
+	}
+	if policy.RejectCommon && isCommonPassword(lowered) {
+		violations = append(violations, PasswordRuleViolation{Rule: "reject_common", Message: "is too common and appears in breached password lists"})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// maxPasswordLength returns the longest password in bytes that the
+// configured hashing algorithm accepts.
+func maxPasswordLength() int {
+	if passwordHashing.Load().Algorithm == AlgorithmBcrypt {
+		return bcryptMaxPasswordBytes
+	}
+	return maxPasswordBytes
+}
+
+func containsIdentity(loweredPassword string, username string, email string) bool {
+	candidates := []string{NormalizeUsername(username), NormalizeEmail(email)}
+	if local, _, found := strings.Cut(NormalizeEmail(email), "@"); found {
+		candidates = append(candidates, local)
+	}
+	for _, candidate := range candidates {
+		// Very short identities would match too many unrelated passwords.
+		if len(candidate) >= 3 && strings.Contains(loweredPassword, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCommonPassword also strips trailing digits and symbols, catching the
+// usual "Password1!" variations of list entries.
+func isCommonPassword(loweredPassword string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = make(map[string]struct{})
+		for _, line := range strings.Split(commonPasswordsFile, "\n") {
+			if entry := strings.TrimSpace(line); entry != "" {
+				commonPasswords[entry] = struct{}{}
+			}
+		}
+	})
+
+	if _, found := commonPasswords[loweredPassword]; found {
+		return true
+	}
+	base := strings.TrimRightFunc(loweredPassword, func(r rune) bool { return !unicode.IsLetter(r) })
+	_, found := commonPasswords[base]
+	return found
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"errors"
+	"movierental/config"
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	ConfigurePasswordPolicy(config.PasswordPolicyConfig{
+		MinLength:        10,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowIdentity: true,
+		RejectCommon:     true,
+	})
+	defer ConfigurePasswordPolicy(config.PasswordPolicyConfig{})
+
+	tests := []struct {
+		name          string
+		password      string
+		expectedRules []string
+	}{
+		{
+			name:          "Strong password",
+			password:      "Correct-Horse-9-Battery",
+			expectedRules: nil,
+		},
+		{
+			name:          "Single character",
+			password:      "a",
+			expectedRules: []string{"min_length", "require_upper", "require_digit", "require_symbol"},
+		},
+		{
+			name:          "Contains username",
+			password:      "Xmoviefan#2024x",
+			expectedRules: []string{"disallow_identity"},
+		},
+		{
+			name:          "Contains email local part",
+			password:      "Fan.Mail+2024!",
+			expectedRules: []string{"disallow_identity"},
+		},
+		{
+			name:          "Common password variant",
+			password:      "Baseball1234!",
+			expectedRules: []string{"reject_common"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password, "moviefan", "fan.mail@example.com")
+			if tt.expectedRules == nil {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Expected PasswordPolicyError, got: %v", err)
+			}
+			if len(policyErr.Violations) != len(tt.expectedRules) {
+				t.Fatalf("Expected violations %v, got %+v", tt.expectedRules, policyErr.Violations)
+			}
+			for i, rule := range tt.expectedRules {
+				if policyErr.Violations[i].Rule != rule {
+					t.Errorf("Expected violation %d to be %q, got %q", i, rule, policyErr.Violations[i].Rule)
+				}
+			}
+		})
+	}
+}
+
+func TestValidatePasswordMaxLength(t *testing.T) {
+	defer ConfigurePasswordHashing(config.PasswordHashingConfig{})
+	hasViolation := func(err error, rule string) bool {
+		var policyErr *PasswordPolicyError
+		if !errors.As(err, &policyErr) {
+			return false
+		}
+		for _, violation := range policyErr.Violations {
+			if violation.Rule == rule {
+				return true
+			}
+		}
+		return false
+	}
+
+	ConfigurePasswordHashing(config.PasswordHashingConfig{Algorithm: AlgorithmBcrypt})
+	if err := ValidatePassword(strings.Repeat("a", 72), "user", "user@example.com"); err != nil {
+		t.Errorf("Expected 72 bytes to be accepted with bcrypt, got: %v", err)
+	}
+	tooLong := strings.Repeat("é", 37)
+	err := ValidatePassword(tooLong, "user", "user@example.com")
+	if !hasViolation(err, "max_length") {
+		t.Fatalf("Expected a max_length violation for 74 bytes with bcrypt, got: %v", err)
+	}
+	if _, hashErr := HashPassword(tooLong); hashErr == nil {
+		t.Error("Expected bcrypt to refuse what the policy rejects")
+	}
+
+	ConfigurePasswordHashing(config.PasswordHashingConfig{Algorithm: AlgorithmArgon2id})
+	if err := ValidatePassword(tooLong, "user", "user@example.com"); err != nil {
+		t.Errorf("Expected argon2id to accept 74 bytes, got: %v", err)
+	}
+	if err := ValidatePassword(strings.Repeat("a", 129), "user", "user@example.com"); !hasViolation(err, "max_length") {
+		t.Errorf("Expected a max_length violation over 128 bytes, got: %v", err)
+	}
+}
+
+func TestValidatePasswordDefaults(t *testing.T) {
+	if err := ValidatePassword("password123", "user", "user@example.com"); err != nil {
+		t.Errorf("Expected default policy to only enforce a minimum length, got: %v", err)
+	}
+	if err := ValidatePassword("short", "user", "user@example.com"); err == nil {
+		t.Error("Expected default policy to reject passwords shorter than 8 characters")
+	}
+}
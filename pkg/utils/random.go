@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// GenerateRandomBytes returns n bytes from the system's secure random source.
+func GenerateRandomBytes(n int) ([]byte, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return buf, nil
+}
+
+// GenerateRandomToken returns n random bytes encoded as unpadded URL-safe
+// base64, suitable for one-time tokens sent to clients.
+func GenerateRandomToken(n int) (string, error) {
+	buf, err := GenerateRandomBytes(n)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
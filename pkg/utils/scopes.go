@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"fmt"
+	"movierental/pkg/models"
+	"slices"
+	"strings"
+)
+
+// OAuth-style scopes carried by access tokens and API keys. A scope ending
+// in ":*" grants every scope with the same resource prefix.
+const (
+	ScopeMoviesRead    = "movies:read"
+	ScopeCartRead      = "cart:read"
+	ScopeCartWrite     = "cart:write"
+	ScopeRentalsRead   = "rentals:read"
+	ScopeRentalsWrite  = "rentals:write"
+	ScopeAccountWrite  = "account:write"
+	ScopeAdminUsers    = "admin:users"
+	ScopeAdminMovieAPI = "admin:movie_api"
+	ScopeAdminAll      = "admin:*"
+)
+
+var knownScopes = []string{
+	ScopeMoviesRead,
+	ScopeCartRead,
+	ScopeCartWrite,
+	ScopeRentalsRead,
+	ScopeRentalsWrite,
+	ScopeAccountWrite,
+	ScopeAdminUsers,
+	ScopeAdminMovieAPI,
+	ScopeAdminAll,
+}
+
+var userScopes = []string{
+	ScopeMoviesRead,
+	ScopeCartRead,
+	ScopeCartWrite,
+	ScopeRentalsRead,
+	ScopeRentalsWrite,
+	ScopeAccountWrite,
+}
+
+// DefaultScopesForRole returns the scopes of a full login token for role.
+func DefaultScopesForRole(role string) []string {
+	scopes := slices.Clone(userScopes)
+	if role == models.RoleAdmin {
+		scopes = append(scopes, ScopeAdminAll)
+	}
+	return scopes
+}
+
+// HasScope reports whether granted includes required, directly or through
+// a wildcard.
+func HasScope(granted []string, required string) bool {
+	for _, scope := range granted {
+		if scope == required {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(scope, "*"); ok && strings.HasPrefix(required, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateScopes checks that every requested scope is known and covered by
+// granted, so a credential can only be narrowed, never widened.
+func ValidateScopes(requested []string, granted []string) error {
+	for _, scope := range requested {
+		if !slices.Contains(knownScopes, scope) {
+			return fmt.Errorf("unknown scope %q", scope)
+		}
+		if !HasScope(granted, scope) {
+			return fmt.Errorf("scope %q exceeds your permissions", scope)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"movierental/pkg/models"
+	"testing"
+)
+
+func TestHasScope(t *testing.T) {
+	tests := []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{ScopeMoviesRead}, ScopeMoviesRead, true},
+		{[]string{ScopeMoviesRead}, ScopeCartWrite, false},
+		{[]string{ScopeAdminAll}, ScopeAdminUsers, true},
+		{[]string{ScopeAdminAll}, ScopeMoviesRead, false},
+		{nil, ScopeMoviesRead, false},
+	}
+	for _, tt := range tests {
+		if got := HasScope(tt.granted, tt.required); got != tt.want {
+			t.Errorf("HasScope(%v, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	userScopes := DefaultScopesForRole(models.RoleUser)
+
+	if err := ValidateScopes([]string{ScopeMoviesRead, ScopeCartRead}, userScopes); err != nil {
+		t.Errorf("Expected subset of user scopes to be valid, got: %v", err)
+	}
+	if err := ValidateScopes([]string{ScopeAdminUsers}, userScopes); err == nil {
+		t.Error("Expected admin scope to exceed user permissions")
+	}
+	if err := ValidateScopes([]string{"movies:delete"}, DefaultScopesForRole(models.RoleAdmin)); err == nil {
+		t.Error("Expected unknown scope to be rejected")
+	}
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	token, err := GenerateAccessToken(TokenClaims{UserID: "user123", Role: models.RoleUser, Scopes: []string{ScopeMoviesRead}})
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+	claims, err := ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if len(claims.Scopes) != 1 || claims.Scopes[0] != ScopeMoviesRead {
+		t.Errorf("Expected only %q, got %v", ScopeMoviesRead, claims.Scopes)
+	}
+
+	legacy, _ := GenerateAccessToken(TokenClaims{UserID: "user123", Email: "test@example.com", Role: models.RoleUser})
+	claims, err = ParseAccessToken(legacy)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if len(claims.Scopes) != 0 {
+		t.Errorf("Expected token without scope claim to grant no scopes, got %v", claims.Scopes)
+	}
+}
@@ -1,25 +1,40 @@
 package utils
 
 import (
+	"context"
+	"movierental/pkg/database/migrations"
 	"movierental/pkg/models"
 	"movierental/pkg/models/requests"
 
+	"github.com/google/uuid"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// SetupTestDB opens a fresh in-memory database, so tests that each set up
+// their own do not share data.
 func SetupTestDB() *gorm.DB {
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	dsn := "file:" + uuid.New().String() + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		panic("Failed to connect to test database")
 	}
-	db.AutoMigrate(&models.User{}, &requests.Cart{})
+	if _, err := migrations.Up(context.Background(), db); err != nil {
+		panic(err)
+	}
 	return db
 }
 
 func ClearTestDB(db *gorm.DB) {
 	db.Exec("DELETE FROM users;")
 	db.Exec("DELETE FROM carts;")
+	db.Exec("DELETE FROM audit_logs;")
+	db.Exec("DELETE FROM mfa_recovery_codes;")
+	db.Exec("DELETE FROM password_reset_tokens;")
+	db.Exec("DELETE FROM user_identities;")
+	db.Exec("DELETE FROM api_keys;")
+	db.Exec("DELETE FROM sessions;")
+	db.Exec("DELETE FROM used_mfa_tokens;")
 }
 
 func CreateTestUserAndCart(db *gorm.DB, userID string, email string) {
@@ -28,3 +43,10 @@ func CreateTestUserAndCart(db *gorm.DB, userID string, email string) {
 	cart := requests.Cart{Id: "cart-" + userID, UserId: userID, Movies: []requests.CartMovieItem{}}
 	db.Create(&cart)
 }
+
+// ConfigureTestSigningKey sets a fixed token signing key for tests.
+func ConfigureTestSigningKey() {
+	if err := ConfigureSigningKey("test-signing-key-for-unit-tests-only"); err != nil {
+		panic(err)
+	}
+}
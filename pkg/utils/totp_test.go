@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Secret is the ASCII string "12345678901234567890" from RFC 6238 appendix B.
+const rfcTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := GenerateTOTPCode(rfcTOTPSecret, TOTPCounter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("GenerateTOTPCode failed: %v", err)
+		}
+		if got != tt.expected {
+			t.Errorf("At %d: expected code %s, got %s", tt.unix, tt.expected, got)
+		}
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	if _, ok := ValidateTOTPCode(rfcTOTPSecret, "005924", now); !ok {
+		t.Error("Expected current code to validate")
+	}
+	if _, ok := ValidateTOTPCode(rfcTOTPSecret, "005924", now.Add(30*time.Second)); !ok {
+		t.Error("Expected previous step code to validate within drift window")
+	}
+	if _, ok := ValidateTOTPCode(rfcTOTPSecret, "005924", now.Add(2*time.Minute)); ok {
+		t.Error("Expected stale code to be rejected")
+	}
+	if _, ok := ValidateTOTPCode(rfcTOTPSecret, "12345", now); ok {
+		t.Error("Expected malformed code to be rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret failed: %v", err)
+	}
+	uri := TOTPURI("Movie Rental", "user@example.com", secret)
+	if !strings.HasPrefix(uri, "otpauth://totp/Movie%20Rental:user@example.com?") {
+		t.Errorf("Unexpected otpauth URI prefix: %s", uri)
+	}
+	if !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("Expected URI to contain the secret, got %s", uri)
+	}
+}
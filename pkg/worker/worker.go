@@ -0,0 +1,53 @@
+// Package worker runs periodic background jobs that stop together when the
+// server shuts down.
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Group runs jobs until Stop is called.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Every runs job once per interval until the group is stopped. The context
+// passed to job is cancelled on Stop, so a run in progress can end early.
+// A non-positive interval disables the job.
+func (g *Group) Every(name string, interval time.Duration, job func(ctx context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-g.ctx.Done():
+				return
+			case <-ticker.C:
+				if err := job(g.ctx); err != nil && g.ctx.Err() == nil {
+					slog.ErrorContext(g.ctx, "Background job failed", "job", name, "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop cancels all jobs and waits for running ones to return.
+func (g *Group) Stop() {
+	g.cancel()
+	g.wg.Wait()
+}
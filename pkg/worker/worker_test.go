@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_EveryRunsUntilStopped(t *testing.T) {
+	group := NewGroup()
+	var runs atomic.Int32
+	done := make(chan struct{})
+	group.Every("count", 5*time.Millisecond, func(ctx context.Context) error {
+		if runs.Add(1) == 3 {
+			close(done)
+		}
+		return nil
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the job to run repeatedly")
+	}
+	group.Stop()
+
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Errorf("Expected no runs after Stop, got %d more", runs.Load()-stopped)
+	}
+}
+
+func TestGroup_StopCancelsRunningJob(t *testing.T) {
+	group := NewGroup()
+	started := make(chan struct{})
+	group.Every("block", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	<-started
+	stopped := make(chan struct{})
+	go func() {
+		group.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected Stop to cancel the running job")
+	}
+}